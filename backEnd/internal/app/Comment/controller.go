@@ -0,0 +1,172 @@
+package comment
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+func CreateThreadController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	// start and end are the paths of the first and last commented characters
+	var req struct {
+		Start []int  `json:"start" binding:"required"`
+		End   []int  `json:"end" binding:"required"`
+		Body  string `json:"body" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	thread, err := createThread(ctx, db, fileUUID, authGuard.UserID(c), req.Start, req.End, req.Body)
+	if !handleCommentError(c, err) {
+		return
+	}
+
+	broadcastEvent(c, EventThreadCreated, fileUUID, thread)
+	c.JSON(http.StatusCreated, gin.H{"thread": thread, "mentions": mentions(c, db, fileUUID, thread.Comments[0])})
+}
+
+// GetThreadsController lists the threads of the file, resolved=true to include the resolved ones.
+func GetThreadsController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	threads, err := listThreads(ctx, db, fileUUID, c.Query("resolved") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding comments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"threads": threads})
+}
+
+func ReplyController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		ThreadID string `json:"thread_id" binding:"required"`
+		Body     string `json:"body" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	comment, err := reply(ctx, db, fileUUID, req.ThreadID, authGuard.UserID(c), req.Body)
+	if !handleCommentError(c, err) {
+		return
+	}
+
+	broadcastEvent(c, EventCommentAdded, fileUUID, comment)
+	c.JSON(http.StatusCreated, gin.H{"comment": comment, "mentions": mentions(c, db, fileUUID, comment)})
+}
+
+func ResolveThreadController(c *gin.Context, db *gorm.DB) {
+	changeResolved(c, db, true)
+}
+
+func ReopenThreadController(c *gin.Context, db *gorm.DB) {
+	changeResolved(c, db, false)
+}
+
+func changeResolved(c *gin.Context, db *gorm.DB, resolved bool) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		ThreadID string `json:"thread_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	thread, err := setResolved(ctx, db, fileUUID, req.ThreadID, authGuard.UserID(c), resolved)
+	if !handleCommentError(c, err) {
+		return
+	}
+
+	event := EventThreadReopened
+	if resolved {
+		event = EventThreadResolved
+	}
+	broadcastEvent(c, event, fileUUID, thread)
+	c.JSON(http.StatusOK, thread)
+}
+
+// DeleteCommentController deletes a comment of the user, the whole thread for its root comment.
+func DeleteCommentController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+	commentID := c.Query("comment_id")
+
+	deleted, err := deleteComment(ctx, db, fileUUID, commentID, authGuard.UserID(c))
+	if !handleCommentError(c, err) {
+		return
+	}
+
+	event := EventCommentDeleted
+	if deleted.ThreadDeleted {
+		event = EventThreadDeleted
+	}
+	broadcastEvent(c, event, fileUUID, deleted)
+	c.JSON(http.StatusOK, deleted)
+}
+
+// mentions notifies the users mentioned in the comment, a failure doesn't fail the comment.
+func mentions(c *gin.Context, db *gorm.DB, fileUUID string, comment CommentData) Mentions {
+	withoutAccess, err := notifyMentions(c.Request.Context(), db, fileUUID, comment)
+	if err != nil {
+		log.Println(err)
+	}
+	return Mentions{
+		WithoutAccess: withoutAccess,
+		CanShare:      len(withoutAccess) > 0 && authGuard.Role(c) == models.RoleOwner,
+	}
+}
+
+// broadcastEvent sends the comment event to every session of the file, the author's included.
+func broadcastEvent(c *gin.Context, event string, fileUUID string, data interface{}) {
+	operation := common.DocumentOperation{
+		OperationType: event,
+		FileUUID:      fileUUID,
+		UserID:        authGuard.UserID(c),
+		Data:          data,
+	}
+	err := redisUtils.BroadcastDocumentOperation(c.Request.Context(), operation)
+	if err != nil {
+		log.Printf("error while broadcasting %s event: %v", event, err)
+	}
+}
+
+// handleCommentError answers the errors of the comment service and returns false if there was one.
+func handleCommentError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrInvalidComment), errors.Is(err, ErrInvalidAnchor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAnchorNotFound), errors.Is(err, ErrThreadNotFound), errors.Is(err, ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
+	"gorm.io/gorm"
+)
+
+const excerptLength = 100
+
+// a mention is @ followed by a username, not preceded by a word character as in an email
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.])@([\p{L}\p{N}_.-]{3,20})`)
+
+// Mentions lists the mentioned users who can't open the file, the commenter is offered to
+// share it with them when they own the file.
+type Mentions struct {
+	WithoutAccess []string `json:"without_access"`
+	CanShare      bool     `json:"can_share"`
+}
+
+// mentionedUsernames returns the usernames mentioned in the body, once each and in order.
+func mentionedUsernames(body string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		if len(username) < 3 || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// excerpt shortens the body of the comment for the notification.
+func excerpt(body string) string {
+	runes := []rune(body)
+	if len(runes) <= excerptLength {
+		return body
+	}
+	return strings.TrimSpace(string(runes[:excerptLength])) + "…"
+}
+
+// notifyMentions sends the mentioned notification to the users mentioned in the comment who
+// can open the file, and returns the ones who can't. The author mentioning themselves is skipped.
+func notifyMentions(ctx context.Context, db *gorm.DB, fileUUID string, comment CommentData) ([]string, error) {
+	withoutAccess := []string{}
+
+	usernames := mentionedUsernames(comment.Body)
+	if len(usernames) == 0 {
+		return withoutAccess, nil
+	}
+	users, err := gorm.G[models.User](db).Select("user_id", "username").Where("username IN ?", usernames).Find(ctx)
+	if err != nil {
+		return withoutAccess, err
+	}
+
+	for _, user := range users {
+		if user.UserID == comment.UserID {
+			continue
+		}
+		_, err := accessUtils.GetRole(user.UserID, fileUUID, ctx, db)
+		if errors.Is(err, accessUtils.ErrNoAccess) {
+			withoutAccess = append(withoutAccess, user.Username)
+			continue
+		}
+		if err != nil {
+			return withoutAccess, err
+		}
+
+		err = notificationUtils.Notify(common.UserNotification{
+			NotificationType: "mentioned",
+			TargetUser:       user.UserID,
+			FileData: common.MentionData{
+				FileUUID:  fileUUID,
+				ThreadID:  comment.ThreadID,
+				CommentID: comment.CommentID,
+				Author:    comment.Username,
+				Excerpt:   excerpt(comment.Body),
+			},
+		}, ctx, db)
+		if err != nil {
+			return withoutAccess, err
+		}
+	}
+	return withoutAccess, nil
+}
@@ -0,0 +1,325 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Websocket events sent to the sessions of the file
+const (
+	EventThreadCreated  = "comment_thread_created"
+	EventCommentAdded   = "comment_added"
+	EventThreadResolved = "comment_thread_resolved"
+	EventThreadReopened = "comment_thread_reopened"
+	EventCommentDeleted = "comment_deleted"
+	EventThreadDeleted  = "comment_thread_deleted"
+)
+
+const maxBodyLength = 5000
+
+var (
+	ErrInvalidComment  = errors.New("comment must be between 1 and 5000 characters")
+	ErrInvalidAnchor   = errors.New("anchor start must not be after its end")
+	ErrAnchorNotFound  = errors.New("anchor characters not found in the file")
+	ErrThreadNotFound  = errors.New("comment thread not found")
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrNotAuthor       = errors.New("only the author can delete a comment")
+)
+
+type CommentData struct {
+	CommentID string `json:"comment_id"`
+	ThreadID  string `json:"thread_id"`
+	UserID    uint32 `json:"user_id"`
+	Username  string `json:"username"`
+	Body      string `json:"body"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Thread is a comment thread with its comments, oldest first. Quote is the text
+// currently visible between the anchors, empty once the passage is deleted.
+type Thread struct {
+	ThreadID   string        `json:"thread_id"`
+	FileUUID   string        `json:"file_uuid"`
+	Start      []int         `json:"start"`
+	End        []int         `json:"end"`
+	Quote      string        `json:"quote"`
+	Resolved   bool          `json:"resolved"`
+	ResolvedBy uint32        `json:"resolved_by"`
+	ResolvedAt int64         `json:"resolved_at"`
+	CreatedBy  uint32        `json:"created_by"`
+	CreatedAt  int64         `json:"created_at"`
+	Comments   []CommentData `json:"comments"`
+}
+
+// DeletedComment tells the sessions which comment is gone, ThreadDeleted when it took its thread.
+type DeletedComment struct {
+	CommentID     string `json:"comment_id"`
+	ThreadID      string `json:"thread_id"`
+	ThreadDeleted bool   `json:"thread_deleted"`
+}
+
+func validBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || len(body) > maxBodyLength {
+		return body, ErrInvalidComment
+	}
+	return body, nil
+}
+
+// createThread anchors a new thread on the passage from start to end, both included,
+// and posts its root comment.
+func createThread(ctx context.Context, db *gorm.DB, fileUUID string, userID uint32, start []int, end []int, body string) (Thread, error) {
+	var thread Thread
+
+	body, err := validBody(body)
+	if err != nil {
+		return thread, err
+	}
+	if len(start) == 0 || len(end) == 0 {
+		return thread, ErrInvalidAnchor
+	}
+	anchorStart := convertUtils.SliceIntToByte(start)
+	anchorEnd := convertUtils.SliceIntToByte(end)
+	if lseqUtils.Compare(anchorStart, anchorEnd) > 0 {
+		return thread, ErrInvalidAnchor
+	}
+
+	count, err := gorm.G[models.FilesContents](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("char_path IN ?", [][]byte{anchorStart, anchorEnd}).
+		Where("deleted = ?", false).
+		Count(ctx, "content_id")
+	if err != nil {
+		return thread, err
+	}
+	expected := int64(2)
+	if lseqUtils.Compare(anchorStart, anchorEnd) == 0 {
+		expected = 1
+	}
+	if count != expected {
+		return thread, ErrAnchorNotFound
+	}
+
+	now := time.Now().Unix()
+	row := models.CommentThread{
+		FileUUID:    fileUUID,
+		AnchorStart: anchorStart,
+		AnchorEnd:   anchorEnd,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := gorm.G[models.CommentThread](tx).Create(ctx, &row)
+		if err != nil {
+			return err
+		}
+		return gorm.G[models.Comment](tx).Create(ctx, &models.Comment{ThreadID: row.ThreadID, UserID: userID, Body: body, Root: true, CreatedAt: now})
+	})
+	if err != nil {
+		return thread, err
+	}
+	return loadThread(ctx, db, fileUUID, row.ThreadID)
+}
+
+// listThreads returns the threads of the file, the resolved ones only with includeResolved.
+func listThreads(ctx context.Context, db *gorm.DB, fileUUID string, includeResolved bool) ([]Thread, error) {
+	query := gorm.G[models.CommentThread](db).Where("file_uuid = ?", fileUUID)
+	if !includeResolved {
+		query = query.Where("resolved = ?", false)
+	}
+	rows, err := query.Order("created_at").Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toThreads(ctx, db, fileUUID, rows)
+}
+
+// loadThread returns one thread of the file with its comments.
+func loadThread(ctx context.Context, db *gorm.DB, fileUUID string, threadID string) (Thread, error) {
+	row, err := findThread(ctx, db, fileUUID, threadID)
+	if err != nil {
+		return Thread{}, err
+	}
+	threads, err := toThreads(ctx, db, fileUUID, []models.CommentThread{row})
+	if err != nil {
+		return Thread{}, err
+	}
+	return threads[0], nil
+}
+
+func findThread(ctx context.Context, db *gorm.DB, fileUUID string, threadID string) (models.CommentThread, error) {
+	row, err := gorm.G[models.CommentThread](db).Where("thread_id = ?", threadID).Where("file_uuid = ?", fileUUID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return row, ErrThreadNotFound
+	}
+	return row, err
+}
+
+// toThreads adds to the rows their comments and the passage they quote.
+func toThreads(ctx context.Context, db *gorm.DB, fileUUID string, rows []models.CommentThread) ([]Thread, error) {
+	threads := make([]Thread, 0, len(rows))
+	if len(rows) == 0 {
+		return threads, nil
+	}
+
+	content, err := document.LoadDocument(ctx, db, fileUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadIDs := make([]string, len(rows))
+	for i, row := range rows {
+		threadIDs[i] = row.ThreadID
+	}
+	var comments []CommentData
+	err = db.Table("comments").
+		Select("comments.comment_id, comments.thread_id, comments.user_id, users.username, comments.body, comments.created_at").
+		Joins("JOIN users ON users.user_id = comments.user_id").
+		Where("comments.thread_id IN ?", threadIDs).
+		Order("comments.root desc, comments.created_at").
+		Scan(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	byThread := map[string][]CommentData{}
+	for _, comment := range comments {
+		byThread[comment.ThreadID] = append(byThread[comment.ThreadID], comment)
+	}
+
+	for _, row := range rows {
+		thread := Thread{
+			ThreadID:   row.ThreadID,
+			FileUUID:   row.FileUUID,
+			Start:      convertUtils.SliceByteToSliceInt(row.AnchorStart),
+			End:        convertUtils.SliceByteToSliceInt(row.AnchorEnd),
+			Resolved:   row.Resolved,
+			ResolvedBy: row.ResolvedBy,
+			ResolvedAt: row.ResolvedAt,
+			CreatedBy:  row.CreatedBy,
+			CreatedAt:  row.CreatedAt,
+			Comments:   byThread[row.ThreadID],
+		}
+		if thread.Comments == nil {
+			thread.Comments = []CommentData{}
+		}
+		thread.Quote = quote(content.Characters, row.AnchorStart, row.AnchorEnd)
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// quote returns the text of the ordered characters whose path is between start and end, included.
+func quote(chars []common.CharacterData, start []byte, end []byte) string {
+	var text strings.Builder
+	for _, char := range chars {
+		path := convertUtils.SliceIntToByte(char.Path)
+		if lseqUtils.Compare(path, start) < 0 {
+			continue
+		}
+		if lseqUtils.Compare(path, end) > 0 {
+			break
+		}
+		text.WriteString(char.Value)
+	}
+	return text.String()
+}
+
+// reply adds a comment at the end of the thread.
+func reply(ctx context.Context, db *gorm.DB, fileUUID string, threadID string, userID uint32, body string) (CommentData, error) {
+	var comment CommentData
+
+	body, err := validBody(body)
+	if err != nil {
+		return comment, err
+	}
+	_, err = findThread(ctx, db, fileUUID, threadID)
+	if err != nil {
+		return comment, err
+	}
+
+	user, err := gorm.G[models.User](db).Where("user_id = ?", userID).First(ctx)
+	if err != nil {
+		return comment, err
+	}
+
+	row := models.Comment{ThreadID: threadID, UserID: userID, Body: body, CreatedAt: time.Now().Unix()}
+	err = gorm.G[models.Comment](db).Create(ctx, &row)
+	if err != nil {
+		return comment, err
+	}
+	return CommentData{
+		CommentID: row.CommentID,
+		ThreadID:  row.ThreadID,
+		UserID:    row.UserID,
+		Username:  user.Username,
+		Body:      row.Body,
+		CreatedAt: row.CreatedAt,
+	}, nil
+}
+
+// setResolved resolves or reopens the thread.
+func setResolved(ctx context.Context, db *gorm.DB, fileUUID string, threadID string, userID uint32, resolved bool) (Thread, error) {
+	updates := map[string]interface{}{"resolved": false, "resolved_by": 0, "resolved_at": 0}
+	if resolved {
+		updates = map[string]interface{}{"resolved": true, "resolved_by": userID, "resolved_at": time.Now().Unix()}
+	}
+
+	result := db.WithContext(ctx).Model(&models.CommentThread{}).
+		Where("thread_id = ?", threadID).
+		Where("file_uuid = ?", fileUUID).
+		Updates(updates)
+	if result.Error != nil {
+		return Thread{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Thread{}, ErrThreadNotFound
+	}
+	return loadThread(ctx, db, fileUUID, threadID)
+}
+
+// deleteComment removes a comment of the user. Deleting the root comment of a thread
+// removes the whole thread with its replies.
+func deleteComment(ctx context.Context, db *gorm.DB, fileUUID string, commentID string, userID uint32) (DeletedComment, error) {
+	var deleted DeletedComment
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		row, err := gorm.G[models.Comment](tx, clause.Locking{Strength: "UPDATE"}).Where("comment_id = ?", commentID).First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, err = findThread(ctx, tx, fileUUID, row.ThreadID)
+		if errors.Is(err, ErrThreadNotFound) {
+			return ErrCommentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if row.UserID != userID {
+			return ErrNotAuthor
+		}
+		deleted = DeletedComment{CommentID: row.CommentID, ThreadID: row.ThreadID}
+
+		if row.Root {
+			deleted.ThreadDeleted = true
+			_, err = gorm.G[models.CommentThread](tx).Where("thread_id = ?", row.ThreadID).Delete(ctx)
+			return err
+		}
+
+		_, err = gorm.G[models.Comment](tx).Where("comment_id = ?", row.CommentID).Delete(ctx)
+		return err
+	})
+	return deleted, err
+}
@@ -0,0 +1,46 @@
+package comment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	chars := []common.CharacterData{
+		{Value: "a", Path: []int{10, 1}},
+		{Value: "b", Path: []int{20, 1}},
+		{Value: "c", Path: []int{20, 1, 5, 2}},
+		{Value: "d", Path: []int{30, 1}},
+	}
+
+	// CASE anchors on visible characters, both included
+	require.Equal(t, "bc", quote(chars, convertUtils.SliceIntToByte([]int{20, 1}), convertUtils.SliceIntToByte([]int{20, 1, 5, 2})))
+
+	// CASE anchor characters deleted, the passage between them is kept
+	require.Equal(t, "bcd", quote(chars, convertUtils.SliceIntToByte([]int{15, 1}), convertUtils.SliceIntToByte([]int{40, 1})))
+
+	// CASE whole passage deleted
+	require.Equal(t, "", quote(chars, convertUtils.SliceIntToByte([]int{21, 1}), convertUtils.SliceIntToByte([]int{25, 1})))
+}
+
+func TestMentionedUsernames(t *testing.T) {
+	// CASE mentions in order, each once
+	require.Equal(t, []string{"alice", "bob_2"}, mentionedUsernames("@alice can you check with @bob_2? cc @alice"))
+
+	// CASE trailing punctuation is not part of the username
+	require.Equal(t, []string{"carol"}, mentionedUsernames("thanks @carol."))
+
+	// CASE emails and too short names are not mentions
+	require.Equal(t, []string{}, mentionedUsernames("mail me at dan@example.com or @ab"))
+}
+
+func TestExcerpt(t *testing.T) {
+	require.Equal(t, "short", excerpt("short"))
+
+	long := strings.Repeat("é", excerptLength+10)
+	require.Equal(t, strings.Repeat("é", excerptLength)+"…", excerpt(long))
+}
@@ -0,0 +1,117 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"gorm.io/gorm"
+)
+
+const OperationBlock = "block"
+
+const attributeBlock = "block"
+
+var ErrNotNewline = errors.New("block attributes can only be set on a newline character")
+
+// SetBlock changes the block attributes of the newline character at the given path,
+// which apply to the whole block it ends. Like formats, concurrent changes resolve by
+// last-writer-wins. Return the revision of the operation and the resulting block.
+func SetBlock(ctx context.Context, db *gorm.DB, author Author, fileUUID string, block common.BlockData) (int64, common.BlockData, error) {
+	if len(block.Path) == 0 {
+		return 0, block, ErrInvalidCharacter
+	}
+	err := models.ValidateBlock(block.Block, block.Level, block.Language)
+	if err != nil {
+		return 0, block, err
+	}
+	block.Previous = nil
+	if block.Timestamp == 0 {
+		block.Timestamp = time.Now().UnixMilli()
+	}
+
+	clock := AttributeClock{Timestamp: block.Timestamp, Site: lseqUtils.SiteFromSession(author.SessionID)}
+
+	var revision int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = nextRevision(ctx, tx, fileUUID)
+		if err != nil {
+			return err
+		}
+
+		char, err := gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("char_path = ?", convertUtils.SliceIntToByte(block.Path)).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCharacterNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if string(char.CharacterValue) != Newline {
+			return ErrNotNewline
+		}
+
+		previous := toCharacterData(char)
+		changed, err := applyBlock(&char, block, clock)
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+				Where("content_id = ?", char.ContentsID).
+				Updates(map[string]interface{}{
+					"block_type":     char.Block,
+					"block_level":    char.BlockLevel,
+					"block_language": char.BlockLanguage,
+					"style_clock":    char.StyleClock,
+				}).Error
+			if err != nil {
+				return err
+			}
+			block.Previous = &previous
+		} else {
+			// a newer change already won, the operation carries the current block
+			block.Block = char.Block
+			block.Level = char.BlockLevel
+			block.Language = char.BlockLanguage
+		}
+
+		return recordOperation(ctx, tx, author, fileUUID, revision, OperationBlock, block)
+	})
+	return revision, block, err
+}
+
+func applyBlock(char *models.FilesContents, block common.BlockData, clock AttributeClock) (bool, error) {
+	clocks := map[string]AttributeClock{}
+	if char.StyleClock != "" {
+		err := json.Unmarshal([]byte(char.StyleClock), &clocks)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if !clock.after(clocks[attributeBlock]) {
+		return false, nil
+	}
+	clocks[attributeBlock] = clock
+
+	bClocks, err := json.Marshal(clocks)
+	if err != nil {
+		return false, err
+	}
+
+	char.Block = block.Block
+	char.BlockLevel = block.Level
+	char.BlockLanguage = block.Language
+	char.StyleClock = string(bClocks)
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBlock(t *testing.T) {
+	char := models.FilesContents{CharacterValue: []byte(Newline), Block: models.BlockParagraph, StyleClock: "{}"}
+
+	changed, err := applyBlock(&char, common.BlockData{Block: models.BlockHeading, Level: 2}, AttributeClock{Timestamp: 10})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, models.BlockHeading, char.Block)
+	require.Equal(t, 2, char.BlockLevel)
+
+	// CASE older change is ignored
+	changed, err = applyBlock(&char, common.BlockData{Block: models.BlockQuote}, AttributeClock{Timestamp: 9})
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, models.BlockHeading, char.Block)
+
+	// CASE block clock is independent from the style clocks
+	_, err = applyFormat(&char, common.FormatData{Set: models.StyleBold}, AttributeClock{Timestamp: 50})
+	require.NoError(t, err)
+	changed, err = applyBlock(&char, common.BlockData{Block: models.BlockCode, Language: "go"}, AttributeClock{Timestamp: 11})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "go", char.BlockLanguage)
+
+	// block data sent to the clients only exists on newlines
+	data := toCharacterData(char)
+	require.Equal(t, models.BlockCode, data.Block)
+	data = toCharacterData(models.FilesContents{CharacterValue: []byte("a"), Block: models.BlockParagraph})
+	require.Empty(t, data.Block)
+}
+
+func TestValidateBlock(t *testing.T) {
+	require.NoError(t, models.ValidateBlock(models.BlockBullet, 3, ""))
+	require.NoError(t, models.ValidateBlock(models.BlockCode, 0, "go"))
+	require.Error(t, models.ValidateBlock(models.BlockHeading, 0, ""))
+	require.Error(t, models.ValidateBlock(models.BlockHeading, 7, ""))
+	require.Error(t, models.ValidateBlock(models.BlockQuote, 0, "go"))
+	require.Error(t, models.ValidateBlock("table", 0, ""))
+}
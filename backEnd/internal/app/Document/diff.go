@@ -0,0 +1,409 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DiffEqual       = "equal"
+	DiffInserted    = "inserted"
+	DiffDeleted     = "deleted"
+	DiffReformatted = "reformatted"
+)
+
+const (
+	GranularityCharacter = "character"
+	GranularityWord      = "word"
+)
+
+var (
+	ErrInvalidRevision    = errors.New("revision is not part of the file history")
+	ErrInvalidReference   = errors.New("reference must be a revision number or a version ID")
+	ErrInvalidGranularity = errors.New("granularity must be character or word")
+)
+
+// DiffRun is a sequence of adjacent characters that changed the same way between two states.
+// Previous holds the attributes the characters of a reformatted run had before.
+type DiffRun struct {
+	Type       string                 `json:"type"`
+	Text       string                 `json:"text"`
+	Characters []common.CharacterData `json:"characters"`
+	Previous   []common.CharacterData `json:"previous,omitempty"`
+	Authors    []uint32               `json:"authors,omitempty"`
+}
+
+// FileDiff is the comparison of two states of a file.
+type FileDiff struct {
+	FileUUID string    `json:"fileUUID"`
+	From     int64     `json:"from"`
+	To       int64     `json:"to"`
+	Runs     []DiffRun `json:"runs"`
+}
+
+type diffEntry struct {
+	kind     string
+	char     common.CharacterData
+	previous common.CharacterData
+}
+
+// Diff compares two contents given in document order. Characters are matched by path,
+// which identifies them for their whole life, so no alignment has to be guessed.
+// With the word granularity, a word touched by an insert or a delete is reported
+// as entirely deleted then inserted.
+func Diff(from []common.CharacterData, to []common.CharacterData, granularity string) ([]DiffRun, error) {
+	if granularity == "" {
+		granularity = GranularityCharacter
+	}
+	if granularity != GranularityCharacter && granularity != GranularityWord {
+		return nil, ErrInvalidGranularity
+	}
+
+	entries := diffEntries(from, to)
+	if granularity == GranularityWord {
+		entries = wordEntries(entries)
+	}
+	return diffRuns(entries), nil
+}
+
+func diffEntries(from []common.CharacterData, to []common.CharacterData) []diffEntry {
+	entries := make([]diffEntry, 0, len(to))
+
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		cmp := 0
+		switch {
+		case i >= len(from):
+			cmp = 1
+		case j >= len(to):
+			cmp = -1
+		default:
+			cmp = lseqUtils.Compare(convertUtils.SliceIntToByte(from[i].Path), convertUtils.SliceIntToByte(to[j].Path))
+		}
+
+		switch {
+		case cmp < 0:
+			entries = append(entries, diffEntry{kind: DiffDeleted, char: from[i]})
+			i++
+		case cmp > 0:
+			entries = append(entries, diffEntry{kind: DiffInserted, char: to[j]})
+			j++
+		default:
+			kind := DiffEqual
+			if !sameAttributes(from[i], to[j]) {
+				kind = DiffReformatted
+			}
+			entries = append(entries, diffEntry{kind: kind, char: to[j], previous: from[i]})
+			i++
+			j++
+		}
+	}
+	return entries
+}
+
+func sameAttributes(a common.CharacterData, b common.CharacterData) bool {
+	return a.Style == b.Style && a.Color == b.Color &&
+		a.Block == b.Block && a.Level == b.Level && a.Language == b.Language
+}
+
+// wordEntries replaces every word containing an insert or a delete by the deletion of
+// its old spelling followed by the insertion of the new one.
+func wordEntries(entries []diffEntry) []diffEntry {
+	words := make([]diffEntry, 0, len(entries))
+
+	for start := 0; start < len(entries); {
+		if isSpace(entries[start].char.Value) {
+			words = append(words, entries[start])
+			start++
+			continue
+		}
+
+		end := start
+		edited := false
+		for end < len(entries) && !isSpace(entries[end].char.Value) {
+			edited = edited || entries[end].kind == DiffInserted || entries[end].kind == DiffDeleted
+			end++
+		}
+
+		if !edited {
+			words = append(words, entries[start:end]...)
+			start = end
+			continue
+		}
+
+		for _, entry := range entries[start:end] {
+			switch entry.kind {
+			case DiffDeleted:
+				words = append(words, entry)
+			case DiffEqual, DiffReformatted:
+				words = append(words, diffEntry{kind: DiffDeleted, char: entry.previous})
+			}
+		}
+		for _, entry := range entries[start:end] {
+			if entry.kind != DiffDeleted {
+				words = append(words, diffEntry{kind: DiffInserted, char: entry.char})
+			}
+		}
+		start = end
+	}
+	return words
+}
+
+func isSpace(value string) bool {
+	return strings.TrimFunc(value, unicode.IsSpace) == ""
+}
+
+func diffRuns(entries []diffEntry) []DiffRun {
+	runs := []DiffRun{}
+
+	for _, entry := range entries {
+		if len(runs) == 0 || runs[len(runs)-1].Type != entry.kind {
+			runs = append(runs, DiffRun{Type: entry.kind, Characters: []common.CharacterData{}})
+		}
+		run := &runs[len(runs)-1]
+
+		run.Text += entry.char.Value
+		run.Characters = append(run.Characters, entry.char)
+		if entry.kind == DiffReformatted {
+			run.Previous = append(run.Previous, entry.previous)
+		}
+	}
+	return runs
+}
+
+// ResolveContent returns the content of the file designated by reference, either
+// a revision number or the ID of one of its versions.
+func ResolveContent(ctx context.Context, db *gorm.DB, fileUUID string, reference string) (Content, error) {
+	revision, err := strconv.ParseInt(reference, 10, 64)
+	if err == nil {
+		return ContentAt(ctx, db, fileUUID, revision)
+	}
+	if uuid.Validate(reference) != nil {
+		return Content{}, ErrInvalidReference
+	}
+
+	version, err := GetVersion(ctx, db, reference)
+	if err != nil {
+		return Content{}, err
+	}
+	if version.FileUUID != fileUUID {
+		return Content{}, ErrVersionNotFound
+	}
+	return Content{FileUUID: fileUUID, Revision: version.Revision, Characters: version.Characters}, nil
+}
+
+// ContentAt rebuilds the visible characters of the file at a past revision by reverting,
+// from the current content, the logged operations that came after it.
+// ErrRevisionCompacted is returned when those operations were folded into a snapshot.
+func ContentAt(ctx context.Context, db *gorm.DB, fileUUID string, revision int64) (Content, error) {
+	content, err := LoadDocument(ctx, db, fileUUID)
+	if err != nil {
+		return content, err
+	}
+	if revision < 0 || revision > content.Revision {
+		return content, ErrInvalidRevision
+	}
+	if revision == content.Revision {
+		return content, nil
+	}
+
+	snapshotRevision, err := snapshotRevisionOf(ctx, db, fileUUID)
+	if err != nil {
+		return content, err
+	}
+	if revision < snapshotRevision {
+		return content, ErrRevisionCompacted
+	}
+
+	rows, err := operationsBetween(ctx, db, fileUUID, revision, content.Revision)
+	if err != nil {
+		return content, err
+	}
+
+	chars := make(map[string]common.CharacterData, len(content.Characters))
+	for _, char := range content.Characters {
+		chars[string(convertUtils.SliceIntToByte(char.Path))] = char
+	}
+
+	for i := len(rows) - 1; i >= 0; i-- {
+		err = rewindOperation(chars, rows[i])
+		if err != nil {
+			return content, err
+		}
+	}
+
+	keys := make([]string, 0, len(chars))
+	for key := range chars {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lseqUtils.Compare([]byte(keys[i]), []byte(keys[j])) < 0
+	})
+
+	content.Revision = revision
+	content.Characters = make([]common.CharacterData, 0, len(keys))
+	for _, key := range keys {
+		content.Characters = append(content.Characters, chars[key])
+	}
+	return content, nil
+}
+
+// operationsBetween returns the logged operations of the file with a revision in (from, to],
+// in revision order.
+func operationsBetween(ctx context.Context, db *gorm.DB, fileUUID string, from int64, to int64) ([]models.DocumentOperation, error) {
+	return gorm.G[models.DocumentOperation](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("revision > ?", from).
+		Where("revision <= ?", to).
+		Order("revision asc").
+		Find(ctx)
+}
+
+// rewindOperation reverts an operation on the characters, keyed by their encoded path.
+func rewindOperation(chars map[string]common.CharacterData, row models.DocumentOperation) error {
+	switch row.OperationType {
+	case OperationInsert:
+		var char common.CharacterData
+		if err := json.Unmarshal([]byte(row.Payload), &char); err != nil {
+			return err
+		}
+		delete(chars, string(convertUtils.SliceIntToByte(char.Path)))
+	case OperationDelete:
+		var char common.CharacterData
+		if err := json.Unmarshal([]byte(row.Payload), &char); err != nil {
+			return err
+		}
+		chars[string(convertUtils.SliceIntToByte(char.Path))] = char
+	case OperationFormat:
+		var format common.FormatData
+		if err := json.Unmarshal([]byte(row.Payload), &format); err != nil {
+			return err
+		}
+		for _, previous := range format.Previous {
+			key := string(convertUtils.SliceIntToByte(previous.Path))
+			char, ok := chars[key]
+			if !ok {
+				continue
+			}
+			char.Style, char.Color = previous.Style, previous.Color
+			chars[key] = char
+		}
+	case OperationBlock:
+		var block common.BlockData
+		if err := json.Unmarshal([]byte(row.Payload), &block); err != nil {
+			return err
+		}
+		if block.Previous == nil {
+			return nil
+		}
+		key := string(convertUtils.SliceIntToByte(block.Path))
+		char, ok := chars[key]
+		if !ok {
+			return nil
+		}
+		char.Block, char.Level, char.Language = block.Previous.Block, block.Previous.Level, block.Previous.Language
+		chars[key] = char
+	}
+	return nil
+}
+
+// CompareContents diffs two states of the file and attributes every changed run to the
+// users whose logged operations between the two states produced it.
+func CompareContents(ctx context.Context, db *gorm.DB, from Content, to Content, granularity string) (FileDiff, error) {
+	diff := FileDiff{FileUUID: from.FileUUID, From: from.Revision, To: to.Revision}
+
+	runs, err := Diff(from.Characters, to.Characters, granularity)
+	if err != nil {
+		return diff, err
+	}
+
+	low, high := from.Revision, to.Revision
+	backward := low > high
+	if backward {
+		low, high = high, low
+	}
+	rows, err := operationsBetween(ctx, db, from.FileUUID, low, high)
+	if err != nil {
+		return diff, err
+	}
+
+	authors, err := operationAuthors(rows)
+	if err != nil {
+		return diff, err
+	}
+
+	for i := range runs {
+		kind := runs[i].Type
+		switch {
+		case kind == DiffEqual:
+			continue
+		case backward && kind == DiffInserted:
+			kind = DiffDeleted
+		case backward && kind == DiffDeleted:
+			kind = DiffInserted
+		}
+
+		seen := map[uint32]bool{}
+		for _, char := range runs[i].Characters {
+			userID, ok := authors[kind][string(convertUtils.SliceIntToByte(char.Path))]
+			if ok && !seen[userID] {
+				seen[userID] = true
+				runs[i].Authors = append(runs[i].Authors, userID)
+			}
+		}
+	}
+
+	diff.Runs = runs
+	return diff, nil
+}
+
+// operationAuthors returns, for each kind of change, the last user who changed each path.
+func operationAuthors(rows []models.DocumentOperation) (map[string]map[string]uint32, error) {
+	authors := map[string]map[string]uint32{
+		DiffInserted:    {},
+		DiffDeleted:     {},
+		DiffReformatted: {},
+	}
+
+	for _, row := range rows {
+		switch row.OperationType {
+		case OperationInsert, OperationDelete:
+			var char common.CharacterData
+			if err := json.Unmarshal([]byte(row.Payload), &char); err != nil {
+				return nil, err
+			}
+			kind := DiffInserted
+			if row.OperationType == OperationDelete {
+				kind = DiffDeleted
+			}
+			authors[kind][string(convertUtils.SliceIntToByte(char.Path))] = row.UserID
+		case OperationFormat:
+			var format common.FormatData
+			if err := json.Unmarshal([]byte(row.Payload), &format); err != nil {
+				return nil, err
+			}
+			for _, char := range format.Characters {
+				authors[DiffReformatted][string(convertUtils.SliceIntToByte(char.Path))] = row.UserID
+			}
+		case OperationBlock:
+			var block common.BlockData
+			if err := json.Unmarshal([]byte(row.Payload), &block); err != nil {
+				return nil, err
+			}
+			authors[DiffReformatted][string(convertUtils.SliceIntToByte(block.Path))] = row.UserID
+		}
+	}
+	return authors, nil
+}
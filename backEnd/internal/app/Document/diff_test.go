@@ -0,0 +1,131 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+)
+
+func diffChars(text string, first int) []common.CharacterData {
+	chars := make([]common.CharacterData, 0, len(text))
+	for i, value := range text {
+		chars = append(chars, common.CharacterData{Value: string(value), Path: []int{(first + i) * 10, 1}})
+	}
+	return chars
+}
+
+func runTypes(runs []DiffRun) [][2]string {
+	types := make([][2]string, 0, len(runs))
+	for _, run := range runs {
+		types = append(types, [2]string{run.Type, run.Text})
+	}
+	return types
+}
+
+func TestDiff(t *testing.T) {
+	// "hello world" becomes "hello brave world" then "world" is put in bold
+	from := diffChars("hello world", 1)
+	to := append([]common.CharacterData{}, from[:6]...)
+	to = append(to, common.CharacterData{Value: "b", Path: []int{65, 2}},
+		common.CharacterData{Value: "r", Path: []int{66, 2}},
+		common.CharacterData{Value: "a", Path: []int{67, 2}},
+		common.CharacterData{Value: "v", Path: []int{68, 2}},
+		common.CharacterData{Value: "e", Path: []int{69, 2}},
+		common.CharacterData{Value: " ", Path: []int{69, 3}},
+	)
+	for _, char := range from[6:] {
+		char.Style = models.StyleBold
+		to = append(to, char)
+	}
+
+	// CASE character granularity
+	runs, err := Diff(from, to, GranularityCharacter)
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{
+		{DiffEqual, "hello "},
+		{DiffInserted, "brave "},
+		{DiffReformatted, "world"},
+	}, runTypes(runs))
+	require.Equal(t, uint32(0), runs[2].Previous[0].Style)
+	require.Equal(t, models.StyleBold, runs[2].Characters[0].Style)
+
+	// CASE identical contents
+	runs, err = Diff(from, from, "")
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{{DiffEqual, "hello world"}}, runTypes(runs))
+
+	// CASE unknown granularity
+	_, err = Diff(from, to, "line")
+	require.ErrorIs(t, err, ErrInvalidGranularity)
+}
+
+func TestDiffWords(t *testing.T) {
+	// "the cat sat" becomes "the cart sat" then "sat" is deleted
+	from := diffChars("the cat sat", 1)
+	to := append([]common.CharacterData{}, from[:6]...)
+	to = append(to, common.CharacterData{Value: "r", Path: []int{65, 2}})
+	to = append(to, from[6:8]...)
+
+	// CASE character granularity only shows the edited letters
+	runs, err := Diff(from, to, GranularityCharacter)
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{
+		{DiffEqual, "the ca"},
+		{DiffInserted, "r"},
+		{DiffEqual, "t "},
+		{DiffDeleted, "sat"},
+	}, runTypes(runs))
+
+	// CASE word granularity replaces the whole edited word
+	runs, err = Diff(from, to, GranularityWord)
+	require.NoError(t, err)
+	require.Equal(t, [][2]string{
+		{DiffEqual, "the "},
+		{DiffDeleted, "cat"},
+		{DiffInserted, "cart"},
+		{DiffEqual, " "},
+		{DiffDeleted, "sat"},
+	}, runTypes(runs))
+}
+
+func TestResolveContent(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "author-session"}
+	insertText(t, author, fileUUID, "abc")
+
+	// CASE a past revision
+	content, err := ResolveContent(t.Context(), db, fileUUID, "2")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), content.Revision)
+	require.Len(t, content.Characters, 2)
+
+	_, err = ResolveContent(t.Context(), db, fileUUID, "4")
+	require.ErrorIs(t, err, ErrInvalidRevision)
+
+	// CASE a version of the file
+	version, err := CreateVersion(t.Context(), db, author.UserID, fileUUID, "v1")
+	require.NoError(t, err)
+	content, err = ResolveContent(t.Context(), db, fileUUID, version.VersionID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), content.Revision)
+	require.Len(t, content.Characters, 3)
+
+	// CASE a version of another file, or none
+	insertFile(t, "22222222-2222-2222-2222-222222222222")
+	_, err = ResolveContent(t.Context(), db, "22222222-2222-2222-2222-222222222222", version.VersionID)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = ResolveContent(t.Context(), db, fileUUID, "33333333-3333-3333-3333-333333333333")
+	require.ErrorIs(t, err, ErrVersionNotFound)
+
+	// CASE neither a revision nor a version ID
+	for _, reference := range []string{"", "last", "1.5", "11111111-1111"} {
+		_, err = ResolveContent(t.Context(), db, fileUUID, reference)
+		require.ErrorIs(t, err, ErrInvalidReference, reference)
+	}
+}
@@ -0,0 +1,162 @@
+package document
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	ExportMarkdown = "md"
+	ExportHTML     = "html"
+	ExportText     = "txt"
+)
+
+var ErrInvalidExportFormat = errors.New("format must be md, html or txt")
+
+// exportPageSize is the number of characters loaded at once by an export.
+const exportPageSize = 1000
+
+var exportContentTypes = map[string]string{
+	ExportMarkdown: "text/markdown; charset=utf-8",
+	ExportHTML:     "text/html; charset=utf-8",
+	ExportText:     "text/plain; charset=utf-8",
+}
+
+// ExportContentType returns the media type of the export format.
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", ErrInvalidExportFormat
+	}
+	return contentType, nil
+}
+
+// renderer writes a document block by block. The characters of a block are only valid
+// during the call, a renderer keeps what it needs of them.
+type renderer interface {
+	open(out *bufio.Writer)
+	writeBlock(out *bufio.Writer, current block)
+	close(out *bufio.Writer)
+}
+
+// block is a paragraph of the document: its characters without the newline ending it,
+// and the attributes carried by this newline.
+type block struct {
+	Type       string
+	Level      int
+	Language   string
+	Characters []common.CharacterData
+}
+
+// WriteExport renders the ordered characters in the format, title names the document
+// in the formats that have one. Only the current block is held in memory. An error of
+// the characters stops the export, the part still buffered is not written.
+func WriteExport(w io.Writer, format string, title string, chars iter.Seq2[common.CharacterData, error]) error {
+	var r renderer
+	switch format {
+	case ExportMarkdown:
+		r = &markdownRenderer{}
+	case ExportHTML:
+		r = &htmlRenderer{title: title}
+	case ExportText:
+		r = textRenderer{}
+	default:
+		return ErrInvalidExportFormat
+	}
+
+	out := bufio.NewWriter(w)
+	r.open(out)
+	// the last block has no newline when the document doesn't end with one, it is a paragraph
+	current := block{Type: models.BlockParagraph}
+	for char, err := range chars {
+		if err != nil {
+			return err
+		}
+		if char.Value != Newline {
+			current.Characters = append(current.Characters, char)
+			continue
+		}
+		current.Type, current.Level, current.Language = char.Block, char.Level, char.Language
+		if current.Type == "" {
+			current.Type = models.BlockParagraph
+		}
+		r.writeBlock(out, current)
+		current = block{Type: models.BlockParagraph, Characters: current.Characters[:0]}
+	}
+	if len(current.Characters) > 0 {
+		r.writeBlock(out, current)
+	}
+	r.close(out)
+	return out.Flush()
+}
+
+// inlineRuns calls write for each run of consecutive characters with the same style,
+// and the same color with withColor. first tells the run starts the block.
+func inlineRuns(chars []common.CharacterData, withColor bool, write func(text string, first bool, attributes common.CharacterData)) {
+	for start := 0; start < len(chars); {
+		end := start
+		var text []byte
+		for end < len(chars) && chars[end].Style == chars[start].Style && (!withColor || chars[end].Color == chars[start].Color) {
+			text = append(text, chars[end].Value...)
+			end++
+		}
+		write(string(text), start == 0, chars[start])
+		start = end
+	}
+}
+
+// DocumentCharacters pages through the visible characters of the file in document order,
+// given by the sort key of their path. Only one page is held in memory and all of them are
+// read from the same snapshot of the file. An error ends the sequence.
+func DocumentCharacters(ctx context.Context, db *gorm.DB, fileUUID string) iter.Seq2[common.CharacterData, error] {
+	return func(yield func(common.CharacterData, error) bool) {
+		stopped := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var after []byte
+			for {
+				query := gorm.G[models.FilesContents](tx).
+					Select("char_path", "sort_key", "char_value", "char_style", "color", "block_type", "block_level", "block_language").
+					Where("file_uuid = ?", fileUUID).
+					Where("deleted = ?", false)
+				if after != nil {
+					query = query.Where("sort_key > ?", after)
+				}
+				chars, err := query.Order("sort_key").Limit(exportPageSize).Find(ctx)
+				if err != nil {
+					return err
+				}
+
+				for i := range chars {
+					if !yield(toCharacterData(chars[i]), nil) {
+						stopped = true
+						return nil
+					}
+				}
+				if len(chars) < exportPageSize {
+					return nil
+				}
+				after = chars[len(chars)-1].SortKey
+			}
+		}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil && !stopped {
+			yield(common.CharacterData{}, err)
+		}
+	}
+}
+
+func isList(blockType string) bool {
+	return blockType == models.BlockBullet || blockType == models.BlockNumbered
+}
+
+func sameCodeBlock(previous *block, current *block) bool {
+	return previous != nil && previous.Type == models.BlockCode && current.Type == models.BlockCode && previous.Language == current.Language
+}
@@ -0,0 +1,338 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"iter"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the exports")
+
+// exportRun is a run of text of a fixture, the block attributes go on its newlines.
+type exportRun struct {
+	Text     string `json:"text"`
+	Style    uint32 `json:"style"`
+	Color    string `json:"color"`
+	Block    string `json:"block"`
+	Level    int    `json:"level"`
+	Language string `json:"language"`
+}
+
+// loadFixture expands the runs of testdata/export/<name>.json into ordered characters.
+func loadFixture(t *testing.T, name string) []common.CharacterData {
+	raw, err := os.ReadFile(filepath.Join("testdata", "export", name+".json"))
+	require.NoError(t, err)
+	var runs []exportRun
+	require.NoError(t, json.Unmarshal(raw, &runs))
+
+	var chars []common.CharacterData
+	for _, run := range runs {
+		for _, value := range run.Text {
+			char := common.CharacterData{Value: string(value), Path: []int{(len(chars) + 1) * 10}, Style: run.Style, Color: run.Color}
+			if char.Value == Newline {
+				char.Style, char.Color = 0, ""
+				char.Block, char.Level, char.Language = run.Block, run.Level, run.Language
+			}
+			chars = append(chars, char)
+		}
+	}
+	return chars
+}
+
+// assertGolden compares the export with testdata/export/<name><extension>, run with -update to rewrite it.
+func assertGolden(t *testing.T, name string, extension string, got []byte) {
+	path := filepath.Join("testdata", "export", name+extension)
+	if *update {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(got))
+}
+
+func TestWriteExport(t *testing.T) {
+	for _, name := range []string{"styles", "blocks", "lists", "escaping", "colors"} {
+		chars := loadFixture(t, name)
+		for _, format := range []string{ExportMarkdown, ExportHTML, ExportText} {
+			t.Run(name+"."+format, func(t *testing.T) {
+				var out bytes.Buffer
+				require.NoError(t, WriteExport(&out, format, "Export <test>", characters(chars)))
+				assertGolden(t, name, "."+format, out.Bytes())
+			})
+		}
+	}
+
+	// CASE empty document
+	var out bytes.Buffer
+	require.NoError(t, WriteExport(&out, ExportMarkdown, "", characters(nil)))
+	require.Empty(t, out.String())
+
+	// CASE unknown format
+	err := WriteExport(&out, "pdf", "", characters(nil))
+	require.ErrorIs(t, err, ErrInvalidExportFormat)
+	_, err = ExportContentType("pdf")
+	require.ErrorIs(t, err, ErrInvalidExportFormat)
+}
+
+// characters yields the characters without error, like a document read in full.
+func characters(chars []common.CharacterData) iter.Seq2[common.CharacterData, error] {
+	return func(yield func(common.CharacterData, error) bool) {
+		for _, char := range chars {
+			if !yield(char, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestDocumentCharacters(t *testing.T) {
+	testenv.CleanTables()
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+
+	// more than two pages of characters, stored in an order that is not the one of the document
+	// and with digits on one and two varint bytes, which the encoded paths misorder
+	var rows []models.FilesContents
+	var expected []string
+	for i := range 2*exportPageSize + 10 {
+		path := []int{100 + i*7, 1}
+		value := string(rune('a' + i%26))
+		deleted := i%5 == 0
+		if !deleted {
+			expected = append(expected, value)
+		}
+		rows = append(rows, models.FilesContents{
+			CharacterValue: []byte(value),
+			Path:           convertUtils.SliceIntToByte(path),
+			SortKey:        lseqUtils.SortKey(lseqUtils.FromInts(path)),
+			Deleted:        deleted,
+			StyleClock:     "{}",
+			Block:          models.BlockParagraph,
+			FileUUID:       fileUUID,
+		})
+	}
+	rand.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+	require.NoError(t, testenv.DB.CreateInBatches(rows, 500).Error)
+
+	// CASE every visible character once, in document order
+	var got []string
+	for char, err := range DocumentCharacters(t.Context(), testenv.DB, fileUUID) {
+		require.NoError(t, err)
+		got = append(got, char.Value)
+	}
+	require.Equal(t, expected, got)
+	require.Equal(t, strings.Join(expected, ""), visibleText(t, fileUUID))
+
+	// CASE the error of a page stops the export
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	var out bytes.Buffer
+	err := WriteExport(&out, ExportText, "", DocumentCharacters(ctx, testenv.DB, fileUUID))
+	require.Error(t, err)
+	require.Empty(t, out.String())
+}
+
+func TestSpanStyle(t *testing.T) {
+	require.Empty(t, spanStyle(0, ""))
+	require.Equal(t, "font-weight:bold;text-decoration:underline line-through", spanStyle(models.StyleBold|models.StyleUnderline|models.StyleStrike, ""))
+	require.Equal(t, "font-family:monospace;color:#0a0", spanStyle(models.StyleCode, "#0a0"))
+	require.Empty(t, spanStyle(0, `red" onclick="x`))
+}
+
+// TestExportRoundTrip reads the HTML exports back with an HTML parser, the characters must
+// come back with their style, color and block attributes. The Markdown export has no reader
+// in the module and is only checked against its golden files.
+func TestExportRoundTrip(t *testing.T) {
+	for _, name := range []string{"styles", "blocks", "lists", "escaping", "colors"} {
+		t.Run(name, func(t *testing.T) {
+			chars := loadFixture(t, name)
+			var out bytes.Buffer
+			require.NoError(t, WriteExport(&out, ExportHTML, name, characters(chars)))
+
+			require.Equal(t, exportedCharacters(chars), readHTMLExport(t, out.Bytes()))
+		})
+	}
+}
+
+// exportedCharacters is what an export keeps of the characters: the paths, the colors
+// that don't reach the CSS and the missing newline of the last block are lost.
+func exportedCharacters(chars []common.CharacterData) []common.CharacterData {
+	var expected []common.CharacterData
+	for _, char := range chars {
+		char.Path = nil
+		if !htmlColorPattern.MatchString(char.Color) {
+			char.Color = ""
+		}
+		if char.Value == Newline && char.Block == "" {
+			char.Block = models.BlockParagraph
+		}
+		expected = append(expected, char)
+	}
+	if len(expected) > 0 && expected[len(expected)-1].Value != Newline {
+		expected = append(expected, common.CharacterData{Value: Newline, Block: models.BlockParagraph})
+	}
+	return expected
+}
+
+// htmlReader rebuilds the characters of an HTML export, each block ends with a newline
+// that carries its attributes.
+type htmlReader struct {
+	chars []common.CharacterData
+}
+
+func readHTMLExport(t *testing.T, export []byte) []common.CharacterData {
+	root, err := html.Parse(bytes.NewReader(export))
+	require.NoError(t, err)
+
+	var body *html.Node
+	for node := range root.Descendants() {
+		if node.Type == html.ElementNode && node.Data == "body" {
+			body = node
+		}
+	}
+	require.NotNil(t, body)
+
+	r := &htmlReader{}
+	r.readBlocks(body, false)
+	return r.chars
+}
+
+// readBlocks reads the block elements under node, quote tells they are in a blockquote.
+func (r *htmlReader) readBlocks(node *html.Node, quote bool) {
+	for child := range node.ChildNodes() {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch child.Data {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			r.readInline(child, 0, "")
+			r.endBlock(models.BlockHeading, int(child.Data[1]-'0'), "")
+		case "p":
+			r.readInline(child, 0, "")
+			if quote {
+				r.endBlock(models.BlockQuote, 0, "")
+			} else {
+				r.endBlock(models.BlockParagraph, 0, "")
+			}
+		case "blockquote":
+			r.readBlocks(child, true)
+		case "ul", "ol":
+			r.readList(child, 0)
+		case "pre":
+			r.readCode(child)
+		}
+	}
+}
+
+// readList reads the items of the list at the depth, the nested lists one level deeper.
+func (r *htmlReader) readList(list *html.Node, level int) {
+	blockType := models.BlockBullet
+	if list.Data == "ol" {
+		blockType = models.BlockNumbered
+	}
+	for item := range list.ChildNodes() {
+		if item.Type != html.ElementNode || item.Data != "li" {
+			continue
+		}
+		r.readInline(item, 0, "")
+		r.endBlock(blockType, level, "")
+		for nested := range item.ChildNodes() {
+			if nested.Type == html.ElementNode && (nested.Data == "ul" || nested.Data == "ol") {
+				r.readList(nested, level+1)
+			}
+		}
+	}
+}
+
+// readCode reads a fenced block, one code block per line.
+func (r *htmlReader) readCode(pre *html.Node) {
+	code := pre.FirstChild
+	language := ""
+	for _, attribute := range code.Attr {
+		if attribute.Key == "class" {
+			language = strings.TrimPrefix(attribute.Val, "language-")
+		}
+	}
+
+	var text strings.Builder
+	for node := range code.Descendants() {
+		if node.Type == html.TextNode {
+			text.WriteString(node.Data)
+		}
+	}
+	for line := range strings.SplitSeq(text.String(), "\n") {
+		r.readText(line, 0, "")
+		r.endBlock(models.BlockCode, 0, language)
+	}
+}
+
+// readInline reads the text of a block with the style and the color of its spans,
+// the nested lists are read as their own blocks.
+func (r *htmlReader) readInline(node *html.Node, style uint32, color string) {
+	for child := range node.ChildNodes() {
+		switch {
+		case child.Type == html.TextNode:
+			// the newlines between the tags are layout, a block never holds one
+			r.readText(strings.ReplaceAll(child.Data, "\n", ""), style, color)
+		case child.Type == html.ElementNode && child.Data == "span":
+			spanStyle, spanColor := style, color
+			for _, attribute := range child.Attr {
+				if attribute.Key == "style" {
+					spanStyle, spanColor = readSpanStyle(attribute.Val)
+				}
+			}
+			r.readInline(child, spanStyle, spanColor)
+		}
+	}
+}
+
+func (r *htmlReader) readText(text string, style uint32, color string) {
+	for _, value := range text {
+		r.chars = append(r.chars, common.CharacterData{Value: string(value), Style: style, Color: color})
+	}
+}
+
+func (r *htmlReader) endBlock(blockType string, level int, language string) {
+	r.chars = append(r.chars, common.CharacterData{Value: Newline, Block: blockType, Level: level, Language: language})
+}
+
+// readSpanStyle is the reverse of spanStyle.
+func readSpanStyle(css string) (style uint32, color string) {
+	for declaration := range strings.SplitSeq(css, ";") {
+		property, value, _ := strings.Cut(declaration, ":")
+		switch property {
+		case "font-weight":
+			style |= models.StyleBold
+		case "font-style":
+			style |= models.StyleItalic
+		case "text-decoration":
+			for decoration := range strings.FieldsSeq(value) {
+				if decoration == "underline" {
+					style |= models.StyleUnderline
+				} else {
+					style |= models.StyleStrike
+				}
+			}
+		case "font-family":
+			style |= models.StyleCode
+		case "color":
+			color = value
+		}
+	}
+	return style, color
+}
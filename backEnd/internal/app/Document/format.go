@@ -0,0 +1,151 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"gorm.io/gorm"
+)
+
+const OperationFormat = "format"
+
+const attributeColor = "color"
+
+var styleAttributes = map[uint32]string{
+	models.StyleBold:      "bold",
+	models.StyleItalic:    "italic",
+	models.StyleUnderline: "underline",
+	models.StyleStrike:    "strike",
+	models.StyleCode:      "code",
+}
+
+var ErrInvalidFormat = errors.New("format must have a range and change at least one attribute")
+
+// AttributeClock is the last-writer-wins timestamp of one attribute of a character.
+// The site breaks ties between formats with the same timestamp.
+type AttributeClock struct {
+	Timestamp int64 `json:"timestamp"`
+	Site      int   `json:"site"`
+}
+
+func (a AttributeClock) after(b AttributeClock) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp > b.Timestamp
+	}
+	return a.Site > b.Site
+}
+
+// FormatRange sets and clears style bits and changes the color of every character of the file
+// between the From and To paths, both included. Each attribute is only changed if the format
+// is newer than the last one applied to it, so concurrent formats converge whatever their order.
+// Return the revision of the operation and the format with the resulting characters.
+func FormatRange(ctx context.Context, db *gorm.DB, author Author, fileUUID string, format common.FormatData) (int64, common.FormatData, error) {
+	if len(format.From) == 0 || len(format.To) == 0 || (format.Set|format.Clear == 0 && format.Color == nil) {
+		return 0, format, ErrInvalidFormat
+	}
+	format.Characters = nil
+	format.Previous = nil
+	if format.Timestamp == 0 {
+		format.Timestamp = time.Now().UnixMilli()
+	}
+
+	clock := AttributeClock{Timestamp: format.Timestamp, Site: lseqUtils.SiteFromSession(author.SessionID)}
+	from := convertUtils.SliceIntToByte(format.From)
+	to := convertUtils.SliceIntToByte(format.To)
+
+	var revision int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = nextRevision(ctx, tx, fileUUID)
+		if err != nil {
+			return err
+		}
+
+		chars, err := gorm.G[models.FilesContents](tx).Where("file_uuid = ?", fileUUID).Find(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, char := range chars {
+			if lseqUtils.Compare(char.Path, from) < 0 || lseqUtils.Compare(char.Path, to) > 0 {
+				continue
+			}
+
+			previous := toCharacterData(char)
+			changed, err := applyFormat(&char, format, clock)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+
+			err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+				Where("content_id = ?", char.ContentsID).
+				Updates(map[string]interface{}{
+					"char_style":  char.Style,
+					"color":       char.Color,
+					"style_clock": char.StyleClock,
+				}).Error
+			if err != nil {
+				return err
+			}
+
+			format.Previous = append(format.Previous, previous)
+			format.Characters = append(format.Characters, toCharacterData(char))
+		}
+
+		return recordOperation(ctx, tx, author, fileUUID, revision, OperationFormat, format)
+	})
+	return revision, format, err
+}
+
+// applyFormat applies to the character every attribute of the format that is newer than
+// the one it already has. Return true if the character changed.
+func applyFormat(char *models.FilesContents, format common.FormatData, clock AttributeClock) (bool, error) {
+	clocks := map[string]AttributeClock{}
+	if char.StyleClock != "" {
+		err := json.Unmarshal([]byte(char.StyleClock), &clocks)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	changed := false
+	for bit, attribute := range styleAttributes {
+		if (format.Set|format.Clear)&bit == 0 || !clock.after(clocks[attribute]) {
+			continue
+		}
+		clocks[attribute] = clock
+		changed = true
+
+		if format.Set&bit != 0 {
+			char.Style |= bit
+		} else {
+			char.Style &^= bit
+		}
+	}
+
+	if format.Color != nil && clock.after(clocks[attributeColor]) {
+		clocks[attributeColor] = clock
+		char.Color = *format.Color
+		changed = true
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	bClocks, err := json.Marshal(clocks)
+	if err != nil {
+		return false, err
+	}
+	char.StyleClock = string(bClocks)
+	return true, nil
+}
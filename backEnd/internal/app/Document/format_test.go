@@ -0,0 +1,63 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFormat(t *testing.T) {
+	char := models.FilesContents{Style: models.StyleItalic, Color: "#000000", StyleClock: "{}"}
+	red := "#ff0000"
+
+	// CASE first format on the character
+	changed, err := applyFormat(&char, common.FormatData{Set: models.StyleBold, Clear: models.StyleItalic, Color: &red}, AttributeClock{Timestamp: 10, Site: 1})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, models.StyleBold, char.Style)
+	require.Equal(t, red, char.Color)
+
+	// CASE older format loses on every attribute it touches
+	blue := "#0000ff"
+	changed, err = applyFormat(&char, common.FormatData{Clear: models.StyleBold, Color: &blue}, AttributeClock{Timestamp: 5, Site: 1})
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Equal(t, models.StyleBold, char.Style)
+	require.Equal(t, red, char.Color)
+
+	// CASE older format still applies on an attribute that was never formatted
+	changed, err = applyFormat(&char, common.FormatData{Set: models.StyleUnderline}, AttributeClock{Timestamp: 5, Site: 1})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, models.StyleBold|models.StyleUnderline, char.Style)
+
+	// CASE same timestamp, the highest site wins
+	changed, err = applyFormat(&char, common.FormatData{Color: &blue}, AttributeClock{Timestamp: 10, Site: 2})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, blue, char.Color)
+}
+
+func TestConcurrentFormatsConverge(t *testing.T) {
+	bold := common.FormatData{Set: models.StyleBold}
+	notBold := common.FormatData{Clear: models.StyleBold}
+	clockA := AttributeClock{Timestamp: 100, Site: 7}
+	clockB := AttributeClock{Timestamp: 100, Site: 3}
+
+	first := models.FilesContents{StyleClock: "{}"}
+	_, err := applyFormat(&first, bold, clockA)
+	require.NoError(t, err)
+	_, err = applyFormat(&first, notBold, clockB)
+	require.NoError(t, err)
+
+	second := models.FilesContents{StyleClock: "{}"}
+	_, err = applyFormat(&second, notBold, clockB)
+	require.NoError(t, err)
+	_, err = applyFormat(&second, bold, clockA)
+	require.NoError(t, err)
+
+	require.Equal(t, first.Style, second.Style)
+	require.Equal(t, models.StyleBold, first.Style)
+}
@@ -0,0 +1,198 @@
+package document
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+)
+
+// htmlHead opens a standalone page, the stylesheet is inlined so the export loads nothing.
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; line-height: 1.5; }
+blockquote { margin-left: 0; padding-left: 1em; border-left: 3px solid #ccc; color: #555; }
+pre { padding: 1em; background: #f5f5f5; overflow-x: auto; }
+</style>
+</head>
+<body>
+`
+
+// colors are free text in the content, only the hex ones and the named ones reach the CSS
+var htmlColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+)$`)
+
+// htmlRenderer writes a self-contained HTML page. The style bits and the color of the
+// characters become inline spans, consecutive quotes, code lines and list items are grouped.
+type htmlRenderer struct {
+	title string
+	// tags of the open lists from the outermost, each one with an open item
+	lists []string
+	quote bool
+	code  *block
+}
+
+func (r *htmlRenderer) open(out *bufio.Writer) {
+	fmt.Fprintf(out, htmlHead, html.EscapeString(r.title))
+}
+
+func (r *htmlRenderer) writeBlock(out *bufio.Writer, current block) {
+	if !sameCodeBlock(r.code, &current) {
+		r.closeCode(out)
+	}
+	if current.Type != models.BlockQuote {
+		r.closeQuote(out)
+	}
+	if !isList(current.Type) {
+		r.closeLists(out, 0)
+	}
+
+	switch current.Type {
+	case models.BlockHeading:
+		level := strconv.Itoa(current.Level)
+		out.WriteString("<h" + level + ">")
+		writeHTMLInline(out, current.Characters)
+		out.WriteString("</h" + level + ">\n")
+	case models.BlockBullet, models.BlockNumbered:
+		r.openItem(out, current)
+		writeHTMLInline(out, current.Characters)
+	case models.BlockQuote:
+		if !r.quote {
+			out.WriteString("<blockquote>\n")
+			r.quote = true
+		}
+		out.WriteString("<p>")
+		writeHTMLInline(out, current.Characters)
+		out.WriteString("</p>\n")
+	case models.BlockCode:
+		if r.code == nil {
+			out.WriteString("<pre><code")
+			if current.Language != "" {
+				out.WriteString(` class="language-` + html.EscapeString(current.Language) + `"`)
+			}
+			out.WriteString(">")
+			r.code = &block{Type: current.Type, Language: current.Language}
+		} else {
+			out.WriteString("\n")
+		}
+		for _, char := range current.Characters {
+			out.WriteString(html.EscapeString(char.Value))
+		}
+	default:
+		out.WriteString("<p>")
+		if len(current.Characters) == 0 {
+			out.WriteString("<br>")
+		}
+		writeHTMLInline(out, current.Characters)
+		out.WriteString("</p>\n")
+	}
+}
+
+func (r *htmlRenderer) close(out *bufio.Writer) {
+	r.closeCode(out)
+	r.closeQuote(out)
+	r.closeLists(out, 0)
+	out.WriteString("</body>\n</html>\n")
+}
+
+// openItem opens the list item, closing the deeper lists and opening the missing ones.
+func (r *htmlRenderer) openItem(out *bufio.Writer, item block) {
+	tag := "ul"
+	if item.Type == models.BlockNumbered {
+		tag = "ol"
+	}
+
+	r.closeLists(out, item.Level+1)
+	if len(r.lists) == item.Level+1 {
+		if r.lists[item.Level] == tag {
+			out.WriteString("</li>\n<li>")
+			return
+		}
+		r.closeLists(out, item.Level)
+	}
+	for len(r.lists) < item.Level+1 {
+		if len(r.lists) > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("<" + tag + ">\n<li>")
+		r.lists = append(r.lists, tag)
+	}
+}
+
+// closeLists closes the open lists deeper than depth.
+func (r *htmlRenderer) closeLists(out *bufio.Writer, depth int) {
+	for len(r.lists) > depth {
+		last := len(r.lists) - 1
+		out.WriteString("</li>\n</" + r.lists[last] + ">")
+		r.lists = r.lists[:last]
+		if len(r.lists) == 0 {
+			out.WriteString("\n")
+		}
+	}
+}
+
+func (r *htmlRenderer) closeQuote(out *bufio.Writer) {
+	if r.quote {
+		out.WriteString("</blockquote>\n")
+		r.quote = false
+	}
+}
+
+func (r *htmlRenderer) closeCode(out *bufio.Writer) {
+	if r.code != nil {
+		out.WriteString("</code></pre>\n")
+		r.code = nil
+	}
+}
+
+// writeHTMLInline writes the characters of a block, the runs with a style or a color in a span.
+func writeHTMLInline(out *bufio.Writer, chars []common.CharacterData) {
+	inlineRuns(chars, true, func(text string, first bool, attributes common.CharacterData) {
+		style := spanStyle(attributes.Style, attributes.Color)
+		if style == "" {
+			out.WriteString(html.EscapeString(text))
+			return
+		}
+		out.WriteString(`<span style="` + style + `">`)
+		out.WriteString(html.EscapeString(text))
+		out.WriteString("</span>")
+	})
+}
+
+// spanStyle returns the inline CSS of the style bits and the color, empty when there is nothing to render.
+func spanStyle(style uint32, color string) string {
+	var declarations []string
+	if style&models.StyleBold != 0 {
+		declarations = append(declarations, "font-weight:bold")
+	}
+	if style&models.StyleItalic != 0 {
+		declarations = append(declarations, "font-style:italic")
+	}
+
+	var decorations []string
+	if style&models.StyleUnderline != 0 {
+		decorations = append(decorations, "underline")
+	}
+	if style&models.StyleStrike != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if len(decorations) > 0 {
+		declarations = append(declarations, "text-decoration:"+strings.Join(decorations, " "))
+	}
+
+	if style&models.StyleCode != 0 {
+		declarations = append(declarations, "font-family:monospace")
+	}
+	if htmlColorPattern.MatchString(color) {
+		declarations = append(declarations, "color:"+color)
+	}
+	return strings.Join(declarations, ";")
+}
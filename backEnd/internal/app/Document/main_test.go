@@ -0,0 +1,61 @@
+package document
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+	redisUtils.CreateRedis(context.Background())
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+func insertUser(t *testing.T, username string) uint32 {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	return user.UserID
+}
+
+func insertFile(t *testing.T, fileUUID string) {
+	require.NoError(t, gorm.G[models.File](testenv.DB).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: fileUUID}))
+}
+
+// insertText inserts the characters of text at the paths [1], [2]... and returns the last revision.
+func insertText(t *testing.T, author Author, fileUUID string, text string) int64 {
+	var revision int64
+	for i, value := range text {
+		var err error
+		revision, err = InsertCharacter(t.Context(), testenv.DB, author, fileUUID, common.CharacterData{Value: string(value), Path: []int{i + 1, 0}})
+		require.NoError(t, err)
+	}
+	return revision
+}
+
+// visibleText returns the text of the file as loaded by the clients.
+func visibleText(t *testing.T, fileUUID string) string {
+	content, err := LoadDocument(t.Context(), testenv.DB, fileUUID)
+	require.NoError(t, err)
+	text := ""
+	for _, char := range content.Characters {
+		text += char.Value
+	}
+	return text
+}
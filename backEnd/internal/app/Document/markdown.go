@@ -0,0 +1,211 @@
+package document
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+)
+
+// markdownStyles are the style bits with a CommonMark delimiter, from the outermost to the
+// innermost. Underline has none and is dropped.
+var markdownStyles = []struct {
+	bit       uint32
+	delimiter string
+}{
+	{models.StyleBold, "**"},
+	{models.StyleItalic, "_"},
+	{models.StyleStrike, "~~"},
+}
+
+// markdownRenderer writes CommonMark. Consecutive list items form one list and consecutive
+// code blocks of the same language one fenced block, an empty paragraph ends them and is skipped.
+type markdownRenderer struct {
+	// previous has the attributes of the last written block, without its characters
+	previous  *block
+	separated bool
+	// per list depth, the number of the last numbered item and the width of the last marker
+	numbers map[int]int
+	widths  map[int]int
+}
+
+func (r *markdownRenderer) open(out *bufio.Writer) {}
+
+func (r *markdownRenderer) writeBlock(out *bufio.Writer, current block) {
+	if current.Type == models.BlockParagraph && len(current.Characters) == 0 {
+		r.separated = true
+		return
+	}
+
+	previous := r.previous
+	continued := previous != nil && !r.separated && (isList(previous.Type) && isList(current.Type) || sameCodeBlock(previous, &current))
+	if previous != nil {
+		if continued {
+			out.WriteString("\n")
+		} else {
+			if previous.Type == models.BlockCode {
+				out.WriteString("\n```")
+			}
+			out.WriteString("\n\n")
+		}
+	}
+	if !continued {
+		r.numbers = map[int]int{}
+		r.widths = map[int]int{}
+	}
+
+	switch current.Type {
+	case models.BlockHeading:
+		out.WriteString(strings.Repeat("#", current.Level) + " ")
+		writeMarkdownInline(out, current.Characters)
+	case models.BlockBullet, models.BlockNumbered:
+		out.WriteString(listMarker(&current, r.numbers, r.widths))
+		writeMarkdownInline(out, current.Characters)
+	case models.BlockQuote:
+		out.WriteString("> ")
+		writeMarkdownInline(out, current.Characters)
+	case models.BlockCode:
+		if !continued {
+			out.WriteString("```" + current.Language + "\n")
+		}
+		for _, char := range current.Characters {
+			out.WriteString(char.Value)
+		}
+	default:
+		writeMarkdownInline(out, current.Characters)
+	}
+	r.previous = &block{Type: current.Type, Level: current.Level, Language: current.Language}
+	r.separated = false
+}
+
+func (r *markdownRenderer) close(out *bufio.Writer) {
+	if r.previous == nil {
+		return
+	}
+	if r.previous.Type == models.BlockCode {
+		out.WriteString("\n```")
+	}
+	out.WriteString("\n")
+}
+
+// listMarker returns the indented marker of the list item. A nested item is indented
+// under the content of its parent, a numbered list restarts after a bullet at its depth.
+func listMarker(item *block, numbers map[int]int, widths map[int]int) string {
+	for level := range numbers {
+		if level > item.Level {
+			delete(numbers, level)
+		}
+	}
+	for level := range widths {
+		if level > item.Level {
+			delete(widths, level)
+		}
+	}
+
+	indent := 0
+	for level := 0; level < item.Level; level++ {
+		width, ok := widths[level]
+		if !ok {
+			width = 2
+		}
+		indent += width
+	}
+
+	marker := "- "
+	if item.Type == models.BlockNumbered {
+		numbers[item.Level]++
+		marker = strconv.Itoa(numbers[item.Level]) + ". "
+	} else {
+		delete(numbers, item.Level)
+	}
+	widths[item.Level] = len(marker)
+	return strings.Repeat(" ", indent) + marker
+}
+
+// writeMarkdownInline writes the characters of a block grouped in runs of the same style.
+// The spaces at the edges of a run are kept out of its delimiters, CommonMark doesn't
+// close an emphasis preceded by a space.
+func writeMarkdownInline(out *bufio.Writer, chars []common.CharacterData) {
+	inlineRuns(chars, false, func(text string, first bool, attributes common.CharacterData) {
+		writeMarkdownRun(out, text, attributes.Style, first)
+	})
+}
+
+func writeMarkdownRun(out *bufio.Writer, text string, style uint32, blockStart bool) {
+	core := strings.TrimSpace(text)
+	if core == "" {
+		out.WriteString(text)
+		return
+	}
+	leading := text[:strings.Index(text, core)]
+	trailing := text[len(leading)+len(core):]
+
+	var open, close strings.Builder
+	for _, markdown := range markdownStyles {
+		if style&markdown.bit != 0 {
+			open.WriteString(markdown.delimiter)
+			close.WriteString(markdown.delimiter)
+		}
+	}
+	closing := []rune(close.String())
+	for i, j := 0, len(closing)-1; i < j; i, j = i+1, j-1 {
+		closing[i], closing[j] = closing[j], closing[i]
+	}
+
+	if style&models.StyleCode != 0 {
+		core = codeSpan(core)
+	} else {
+		core = escapeMarkdown(core, blockStart && leading == "" && open.Len() == 0)
+	}
+
+	out.WriteString(leading)
+	out.WriteString(open.String())
+	out.WriteString(core)
+	out.WriteString(string(closing))
+	out.WriteString(trailing)
+}
+
+// codeSpan wraps the text in enough backticks to keep the ones it contains.
+func codeSpan(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if longest > 0 {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+// escapeMarkdown escapes the characters that would be read as markdown syntax,
+// lineStart also escapes the ones that only start a block at the beginning of a line.
+func escapeMarkdown(text string, lineStart bool) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("\\`*_~[]<>", r) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	result := escaped.String()
+	if !lineStart {
+		return result
+	}
+
+	if strings.ContainsRune("#>-+", rune(result[0])) {
+		return "\\" + result
+	}
+	digits := len(result) - len(strings.TrimLeft(result, "0123456789"))
+	if digits > 0 && digits < len(result) && (result[digits] == '.' || result[digits] == ')') {
+		return result[:digits] + "\\" + result[digits:]
+	}
+	return result
+}
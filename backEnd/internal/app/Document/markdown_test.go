@@ -0,0 +1,17 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	require.Equal(t, `a\*b\_c`, escapeMarkdown("a*b_c", false))
+	require.Equal(t, `\# title`, escapeMarkdown("# title", true))
+	require.Equal(t, "# title", escapeMarkdown("# title", false))
+	require.Equal(t, `12\. point`, escapeMarkdown("12. point", true))
+	require.Equal(t, "12", escapeMarkdown("12", true))
+	require.Equal(t, "`a b`", codeSpan("a b"))
+	require.Equal(t, "`` a`b ``", codeSpan("a`b"))
+}
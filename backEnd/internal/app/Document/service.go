@@ -0,0 +1,318 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"gorm.io/gorm"
+)
+
+const (
+	OperationInsert = "insert"
+	OperationDelete = "delete"
+)
+
+// Newline ends a block and carries its attributes.
+const Newline = "\n"
+
+var (
+	ErrInvalidCharacter  = errors.New("character must be a single rune at a path of digit and site pairs")
+	ErrCharacterNotFound = errors.New("character not found in file")
+	ErrFileNotFound      = errors.New("file not found")
+)
+
+// Content is the visible state of a document at a given revision.
+type Content struct {
+	FileUUID   string                 `json:"fileUUID"`
+	Revision   int64                  `json:"revision"`
+	Characters []common.CharacterData `json:"characters"`
+}
+
+// AllocatePath gives the character a path strictly between its left and right neighbours
+// when the client didn't choose one. The site of the session breaks ties with concurrent inserts.
+func AllocatePath(char *common.CharacterData, sessionID string) error {
+	if len(char.Path) != 0 {
+		return nil
+	}
+
+	position, err := lseqUtils.Alloc(lseqUtils.FromInts(char.Left), lseqUtils.FromInts(char.Right), lseqUtils.SiteFromSession(sessionID))
+	if err != nil {
+		return err
+	}
+
+	char.Path = position.Ints()
+	char.Left = nil
+	char.Right = nil
+	return nil
+}
+
+// validCharacter checks that the character is a single rune at a valid path.
+func validCharacter(char common.CharacterData) bool {
+	return utf8.ValidString(char.Value) && utf8.RuneCountInString(char.Value) == 1 && validPath(char.Path)
+}
+
+// validPath checks that the path is made of (digit, site) pairs without negative entries,
+// the encoding every stored path and sort key relies on.
+func validPath(path []int) bool {
+	if len(path) == 0 || len(path)%2 != 0 {
+		return false
+	}
+	for _, n := range path {
+		if n < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Author identifies the user and the session an operation comes from.
+type Author struct {
+	UserID    uint32
+	SessionID string
+}
+
+// InsertCharacter stores a new character of the file at the position given by its path
+// and records the operation in the log. Return the revision of the operation.
+func InsertCharacter(ctx context.Context, db *gorm.DB, author Author, fileUUID string, char common.CharacterData) (int64, error) {
+	if !validCharacter(char) {
+		return 0, ErrInvalidCharacter
+	}
+
+	block, level, language := models.BlockParagraph, 0, ""
+	if char.Value == Newline && char.Block != "" {
+		err := models.ValidateBlock(char.Block, char.Level, char.Language)
+		if err != nil {
+			return 0, err
+		}
+		block, level, language = char.Block, char.Level, char.Language
+	}
+
+	var revision int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = nextRevision(ctx, tx, fileUUID)
+		if err != nil {
+			return err
+		}
+
+		err = gorm.G[models.FilesContents](tx).Create(ctx, &models.FilesContents{
+			CharacterValue: []byte(char.Value),
+			Path:           convertUtils.SliceIntToByte(char.Path),
+			SortKey:        lseqUtils.SortKey(lseqUtils.FromInts(char.Path)),
+			Style:          char.Style,
+			Color:          char.Color,
+			InsertedRev:    revision,
+			StyleClock:     "{}",
+			Block:          block,
+			BlockLevel:     level,
+			BlockLanguage:  language,
+			FileUUID:       fileUUID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordOperation(ctx, tx, author, fileUUID, revision, OperationInsert, char)
+	})
+	return revision, err
+}
+
+// DeleteCharacter tombstones the character of the file at the given path and records
+// the operation in the log. The row is kept so that concurrent operations referencing
+// this position still resolve. Return the revision of the operation.
+func DeleteCharacter(ctx context.Context, db *gorm.DB, author Author, fileUUID string, path []int) (int64, error) {
+	if !validPath(path) {
+		return 0, ErrInvalidCharacter
+	}
+
+	var revision int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = nextRevision(ctx, tx, fileUUID)
+		if err != nil {
+			return err
+		}
+
+		char, err := gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("char_path = ?", convertUtils.SliceIntToByte(path)).
+			Where("deleted = ?", false).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCharacterNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = gorm.G[models.FilesContents](tx).
+			Where("content_id = ?", char.ContentsID).
+			Updates(ctx, models.FilesContents{Deleted: true, DeletedRev: revision})
+		if err != nil {
+			return err
+		}
+
+		// the whole character is logged so the delete can be reverted
+		return recordOperation(ctx, tx, author, fileUUID, revision, OperationDelete, toCharacterData(char))
+	})
+	return revision, err
+}
+
+// CurrentRevision returns the revision of the last operation on the file.
+func CurrentRevision(ctx context.Context, db *gorm.DB, fileUUID string) (int64, error) {
+	file, err := gorm.G[models.File](db).Where("file_uuid = ?", fileUUID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrFileNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return file.FileRevision, nil
+}
+
+// nextRevision increments the revision of the file. The row lock taken by the update
+// orders the concurrent operations on the same file until the transaction ends.
+func nextRevision(ctx context.Context, tx *gorm.DB, fileUUID string) (int64, error) {
+	var revision int64
+	err := tx.WithContext(ctx).
+		Raw("UPDATE files SET file_revision = file_revision + 1, file_updated_at = ? WHERE file_uuid = ? RETURNING file_revision", time.Now().Unix(), fileUUID).
+		Scan(&revision).Error
+	if err != nil {
+		return 0, err
+	}
+	if revision == 0 {
+		return 0, ErrFileNotFound
+	}
+	return revision, nil
+}
+
+func recordOperation(ctx context.Context, tx *gorm.DB, author Author, fileUUID string, revision int64, operationType string, data interface{}) error {
+	return recordOperationKind(ctx, tx, author, fileUUID, revision, operationType, data, OperationKindEdit, 0)
+}
+
+// recordOperationKind records an operation, undoOf being the revision an undo or redo reverts.
+func recordOperationKind(ctx context.Context, tx *gorm.DB, author Author, fileUUID string, revision int64, operationType string, data interface{}, kind string, undoOf int64) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return gorm.G[models.DocumentOperation](tx).Create(ctx, &models.DocumentOperation{
+		FileUUID:      fileUUID,
+		Revision:      revision,
+		OperationType: operationType,
+		UserID:        author.UserID,
+		SessionID:     author.SessionID,
+		Payload:       string(payload),
+		Kind:          kind,
+		UndoOf:        undoOf,
+		CreatedAt:     time.Now().Unix(),
+	})
+}
+
+// OperationsSince returns the logged operations of the file with a revision strictly
+// greater than since, in revision order.
+// ErrRevisionCompacted is returned when some of them were already folded into a snapshot,
+// the client must then reload the whole document.
+func OperationsSince(ctx context.Context, db *gorm.DB, fileUUID string, since int64) ([]common.DocumentOperation, error) {
+	snapshotRevision, err := snapshotRevisionOf(ctx, db, fileUUID)
+	if err != nil {
+		return nil, err
+	}
+	if since < snapshotRevision {
+		return nil, ErrRevisionCompacted
+	}
+
+	rows, err := gorm.G[models.DocumentOperation](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("revision > ?", since).
+		Order("revision asc").
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]common.DocumentOperation, 0, len(rows))
+	for _, row := range rows {
+		operations = append(operations, toDocumentOperation(row))
+	}
+	return operations, nil
+}
+
+func toDocumentOperation(row models.DocumentOperation) common.DocumentOperation {
+	return common.DocumentOperation{
+		OperationType: row.OperationType,
+		FileUUID:      row.FileUUID,
+		SessionID:     row.SessionID,
+		UserID:        row.UserID,
+		Revision:      row.Revision,
+		Data:          json.RawMessage(row.Payload),
+	}
+}
+
+// LoadDocument returns the visible characters of the file ordered by their decoded path,
+// along with the revision they correspond to.
+func LoadDocument(ctx context.Context, db *gorm.DB, fileUUID string) (Content, error) {
+	content := Content{
+		FileUUID:   fileUUID,
+		Characters: []common.CharacterData{},
+	}
+
+	var chars []models.FilesContents
+	err := db.Transaction(func(tx *gorm.DB) error {
+		file, err := gorm.G[models.File](tx).Where("file_uuid = ?", fileUUID).First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		if err != nil {
+			return err
+		}
+		content.Revision = file.FileRevision
+
+		chars, err = gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("deleted = ?", false).
+			Find(ctx)
+		return err
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return content, err
+	}
+
+	sortCharacters(chars)
+
+	for _, char := range chars {
+		content.Characters = append(content.Characters, toCharacterData(char))
+	}
+	return content, nil
+}
+
+func sortCharacters(chars []models.FilesContents) {
+	sort.SliceStable(chars, func(i, j int) bool {
+		return lseqUtils.Compare(chars[i].Path, chars[j].Path) < 0
+	})
+}
+
+func toCharacterData(char models.FilesContents) common.CharacterData {
+	data := common.CharacterData{
+		Value: string(char.CharacterValue),
+		Path:  convertUtils.SliceByteToSliceInt(char.Path),
+		Style: char.Style,
+		Color: char.Color,
+	}
+	if data.Value == Newline {
+		data.Block = char.Block
+		data.Level = char.BlockLevel
+		data.Language = char.BlockLanguage
+	}
+	return data
+}
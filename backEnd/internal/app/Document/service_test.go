@@ -0,0 +1,156 @@
+package document
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationsSince(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	first := Author{UserID: insertUser(t, "first"), SessionID: "first-session"}
+	second := Author{UserID: insertUser(t, "second"), SessionID: "second-session"}
+
+	// CASE no operation yet
+	operations, err := OperationsSince(t.Context(), db, fileUUID, 0)
+	require.NoError(t, err)
+	require.Empty(t, operations)
+
+	// CASE every operation gets the next revision of the file
+	require.Equal(t, int64(2), insertText(t, first, fileUUID, "ab"))
+	revision, err := InsertCharacter(t.Context(), db, second, fileUUID, common.CharacterData{Value: "c", Path: []int{3, 0}, Style: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), revision)
+	revision, err = DeleteCharacter(t.Context(), db, second, fileUUID, []int{1, 0})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), revision)
+
+	currentRevision, err := CurrentRevision(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), currentRevision)
+
+	// CASE the operations after a revision, in order, with their author and payload
+	operations, err = OperationsSince(t.Context(), db, fileUUID, 2)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+
+	require.Equal(t, OperationInsert, operations[0].OperationType)
+	require.Equal(t, int64(3), operations[0].Revision)
+	require.Equal(t, second.UserID, operations[0].UserID)
+	require.Equal(t, second.SessionID, operations[0].SessionID)
+	var char common.CharacterData
+	require.NoError(t, json.Unmarshal(operations[0].Data.(json.RawMessage), &char))
+	require.Equal(t, common.CharacterData{Value: "c", Path: []int{3, 0}, Style: 1}, char)
+
+	// the delete logs the whole character so it can be reverted
+	require.Equal(t, OperationDelete, operations[1].OperationType)
+	require.Equal(t, int64(4), operations[1].Revision)
+	require.NoError(t, json.Unmarshal(operations[1].Data.(json.RawMessage), &char))
+	require.Equal(t, common.CharacterData{Value: "a", Path: []int{1, 0}}, char)
+
+	operations, err = OperationsSince(t.Context(), db, fileUUID, 4)
+	require.NoError(t, err)
+	require.Empty(t, operations)
+
+	// CASE a failed operation neither logs nor takes a revision
+	_, err = DeleteCharacter(t.Context(), db, first, fileUUID, []int{1, 0})
+	require.ErrorIs(t, err, ErrCharacterNotFound)
+	_, err = InsertCharacter(t.Context(), db, first, fileUUID, common.CharacterData{Value: "d"})
+	require.ErrorIs(t, err, ErrInvalidCharacter)
+
+	currentRevision, err = CurrentRevision(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), currentRevision)
+	operations, err = OperationsSince(t.Context(), db, fileUUID, 0)
+	require.NoError(t, err)
+	require.Len(t, operations, 4)
+
+	// CASE unknown file
+	_, err = InsertCharacter(t.Context(), db, first, "33333333-3333-3333-3333-333333333333", common.CharacterData{Value: "d", Path: []int{4, 0}})
+	require.ErrorIs(t, err, ErrFileNotFound)
+	_, err = CurrentRevision(t.Context(), db, "33333333-3333-3333-3333-333333333333")
+	require.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestConcurrentRevisions(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "concurrent-session"}
+
+	const inserts = 20
+	revisions := make([]int64, inserts)
+	errs := make([]error, inserts)
+	var wg sync.WaitGroup
+	for i := range inserts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			revisions[i], errs[i] = InsertCharacter(t.Context(), db, author, fileUUID, common.CharacterData{Value: "a", Path: []int{i + 1, 0}})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// CASE the concurrent operations get distinct revisions, without gap
+	seen := make(map[int64]bool, inserts)
+	for _, revision := range revisions {
+		require.False(t, seen[revision])
+		require.True(t, revision >= 1 && revision <= inserts)
+		seen[revision] = true
+	}
+
+	operations, err := OperationsSince(t.Context(), db, fileUUID, 0)
+	require.NoError(t, err)
+	require.Len(t, operations, inserts)
+	for i, operation := range operations {
+		require.Equal(t, int64(i+1), operation.Revision)
+	}
+}
+
+func TestInvalidCharacter(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "invalid-session"}
+
+	// CASE paths that aren't (digit, site) pairs, values that aren't a single rune
+	for _, char := range []common.CharacterData{
+		{Value: "a"},
+		{Value: "a", Path: []int{5, 1, 7}},
+		{Value: "a", Path: []int{5, -1}},
+		{Value: "a", Path: []int{-5, 1}},
+		{Value: "", Path: []int{5, 1}},
+		{Value: "ab", Path: []int{5, 1}},
+		{Value: "\xff", Path: []int{5, 1}},
+	} {
+		_, err := InsertCharacter(t.Context(), db, author, fileUUID, char)
+		require.ErrorIs(t, err, ErrInvalidCharacter, char)
+		_, err = SuggestInsert(t.Context(), db, author, fileUUID, char)
+		require.ErrorIs(t, err, ErrInvalidCharacter, char)
+	}
+	_, err := DeleteCharacter(t.Context(), db, author, fileUUID, []int{5, 1, 7})
+	require.ErrorIs(t, err, ErrInvalidCharacter)
+	_, err = SuggestDelete(t.Context(), db, author, fileUUID, []int{5})
+	require.ErrorIs(t, err, ErrInvalidCharacter)
+
+	// CASE a multi-byte rune is a single character
+	_, err = InsertCharacter(t.Context(), db, author, fileUUID, common.CharacterData{Value: "é", Path: []int{5, 1}})
+	require.NoError(t, err)
+	require.Equal(t, "é", visibleText(t, fileUUID))
+
+	revision, err := CurrentRevision(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), revision)
+}
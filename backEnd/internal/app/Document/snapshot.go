@@ -0,0 +1,155 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Operations younger than compactionDelay are kept in the log so that
+// clients reconnecting after a short drop can still catch up.
+const (
+	compactionInterval = time.Hour
+	compactionDelay    = time.Hour
+)
+
+var ErrRevisionCompacted = errors.New("revision is older than the last snapshot")
+
+// CompactDocuments periodically folds the operation logs into a snapshot per document
+// and garbage-collects the tombstones every connected session has acknowledged.
+func CompactDocuments(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(compactionInterval)
+	defer ticker.Stop()
+
+	compactDocuments(ctx, db)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("CompactDocuments stopped:", ctx.Err())
+			return
+
+		case <-ticker.C:
+			compactDocuments(ctx, db)
+		}
+	}
+}
+
+func compactDocuments(ctx context.Context, db *gorm.DB) {
+	var fileUUIDs []string
+	err := db.WithContext(ctx).
+		Table("files").
+		Joins("LEFT JOIN document_snapshots ON document_snapshots.file_uuid = files.file_uuid").
+		Where(`files.file_revision > COALESCE(document_snapshots.revision, 0) OR EXISTS (
+			SELECT 1 FROM document_operations
+			WHERE document_operations.file_uuid = files.file_uuid
+			AND document_operations.revision <= document_snapshots.revision
+		)`).
+		Pluck("files.file_uuid", &fileUUIDs).Error
+	if err != nil {
+		fmt.Printf("Error finding documents to compact: %v\n", err)
+		return
+	}
+
+	for _, fileUUID := range fileUUIDs {
+		err := CompactDocument(ctx, db, fileUUID)
+		if err != nil {
+			fmt.Printf("Error compacting document %s: %v\n", fileUUID, err)
+		}
+	}
+}
+
+// CompactDocument records a snapshot of the file at the highest revision that is both
+// acknowledged by every connected session and older than the compaction delay. The
+// operations up to this revision are removed from the log, and so are the characters
+// deleted up to it. The operations of the connected sessions are kept for their undo
+// and redo, they are removed by a later compaction once the session is gone.
+func CompactDocument(ctx context.Context, db *gorm.DB, fileUUID string) error {
+	var snapshotRevision int64
+	err := db.WithContext(ctx).
+		Model(&models.DocumentOperation{}).
+		Where("file_uuid = ?", fileUUID).
+		Where("created_at < ?", time.Now().Add(-compactionDelay).Unix()).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&snapshotRevision).Error
+	if err != nil {
+		return err
+	}
+
+	sessions, err := redisUtils.GetConnectedSessions(fileUUID, ctx)
+	if err != nil {
+		return err
+	}
+	connected := make([]string, 0, len(sessions))
+	for sessionUUID, acknowledged := range sessions {
+		connected = append(connected, sessionUUID)
+		if acknowledged < snapshotRevision {
+			snapshotRevision = acknowledged
+		}
+	}
+
+	previousRevision, err := snapshotRevisionOf(ctx, db, fileUUID)
+	if err != nil {
+		return err
+	}
+	if snapshotRevision < previousRevision {
+		snapshotRevision = previousRevision
+	}
+	if snapshotRevision == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("deleted = ?", true).
+			Where("deleted_revision <= ?", snapshotRevision).
+			Delete(ctx)
+		if err != nil {
+			return err
+		}
+
+		operations := gorm.G[models.DocumentOperation](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("revision <= ?", snapshotRevision)
+		if len(connected) > 0 {
+			operations = operations.Where("session_id NOT IN ?", connected)
+		}
+		_, err = operations.Delete(ctx)
+		if err != nil {
+			return err
+		}
+
+		if snapshotRevision == previousRevision {
+			return nil
+		}
+		return tx.WithContext(ctx).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "file_uuid"}},
+				DoUpdates: clause.AssignmentColumns([]string{"revision", "created_at"}),
+			}).
+			Create(&models.DocumentSnapshot{
+				FileUUID:  fileUUID,
+				Revision:  snapshotRevision,
+				CreatedAt: time.Now().Unix(),
+			}).Error
+	})
+}
+
+// snapshotRevisionOf returns the revision of the last snapshot of the file, 0 if there is none.
+func snapshotRevisionOf(ctx context.Context, db *gorm.DB, fileUUID string) (int64, error) {
+	snapshot, err := gorm.G[models.DocumentSnapshot](db).Where("file_uuid = ?", fileUUID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return snapshot.Revision, nil
+}
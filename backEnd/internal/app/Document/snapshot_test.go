@@ -0,0 +1,125 @@
+package document
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// ageOperations makes the logged operations of the file older than the compaction delay.
+func ageOperations(t *testing.T, fileUUID string) {
+	err := testenv.DB.Model(&models.DocumentOperation{}).
+		Where("file_uuid = ?", fileUUID).
+		Update("created_at", time.Now().Add(-2*compactionDelay).Unix()).Error
+	require.NoError(t, err)
+}
+
+func countRows[T any](t *testing.T, fileUUID string) int64 {
+	count, err := gorm.G[T](testenv.DB).Where("file_uuid = ?", fileUUID).Count(t.Context(), "*")
+	require.NoError(t, err)
+	return count
+}
+
+func TestCompactDocument(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "compaction-author"}
+
+	insertText(t, author, fileUUID, "abc")
+	_, err := DeleteCharacter(t.Context(), db, author, fileUUID, []int{2, 0})
+	require.NoError(t, err)
+
+	// CASE the operations younger than the compaction delay are kept
+	require.NoError(t, CompactDocument(t.Context(), db, fileUUID))
+	require.Equal(t, int64(4), countRows[models.DocumentOperation](t, fileUUID))
+	require.Equal(t, int64(0), countRows[models.DocumentSnapshot](t, fileUUID))
+
+	// CASE the snapshot stops at the revision acknowledged by a connected session
+	reader := "compaction-reader"
+	redisUtils.StoreSessionInRedis(author.UserID, reader, t.Context())
+	defer redisUtils.DeleteSessionInRedis(reader, t.Context())
+	redisUtils.SetSessionRevision(fileUUID, reader, 2, t.Context())
+	defer redisUtils.DeleteSessionRevision(fileUUID, reader, t.Context())
+
+	ageOperations(t, fileUUID)
+	require.NoError(t, CompactDocument(t.Context(), db, fileUUID))
+
+	snapshotRevision, err := snapshotRevisionOf(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), snapshotRevision)
+	require.Equal(t, int64(2), countRows[models.DocumentOperation](t, fileUUID))
+	// the character deleted at revision 4 is still needed by the reader
+	require.Equal(t, int64(3), countRows[models.FilesContents](t, fileUUID))
+
+	// CASE OperationsSince below the snapshot
+	_, err = OperationsSince(t.Context(), db, fileUUID, 1)
+	require.ErrorIs(t, err, ErrRevisionCompacted)
+
+	operations, err := OperationsSince(t.Context(), db, fileUUID, 2)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	require.Equal(t, int64(3), operations[0].Revision)
+
+	// CASE once acknowledged, the tombstones are removed and the visible characters kept
+	redisUtils.SetSessionRevision(fileUUID, reader, 4, t.Context())
+	require.NoError(t, CompactDocument(t.Context(), db, fileUUID))
+
+	snapshotRevision, err = snapshotRevisionOf(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), snapshotRevision)
+	require.Equal(t, int64(0), countRows[models.DocumentOperation](t, fileUUID))
+	require.Equal(t, int64(2), countRows[models.FilesContents](t, fileUUID))
+	require.Equal(t, "ac", visibleText(t, fileUUID))
+
+	_, err = OperationsSince(t.Context(), db, fileUUID, 3)
+	require.ErrorIs(t, err, ErrRevisionCompacted)
+	operations, err = OperationsSince(t.Context(), db, fileUUID, 4)
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
+
+func TestCompactDocumentKeepsUndo(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "compaction-undo"}
+
+	redisUtils.StoreSessionInRedis(author.UserID, author.SessionID, t.Context())
+	defer redisUtils.DeleteSessionInRedis(author.SessionID, t.Context())
+	defer redisUtils.DeleteSessionRevision(fileUUID, author.SessionID, t.Context())
+
+	insertText(t, author, fileUUID, "ab")
+	_, err := DeleteCharacter(t.Context(), db, author, fileUUID, []int{2, 0})
+	require.NoError(t, err)
+	redisUtils.SetSessionRevision(fileUUID, author.SessionID, 3, t.Context())
+
+	// CASE the operations of a connected session survive the compaction
+	ageOperations(t, fileUUID)
+	require.NoError(t, CompactDocument(t.Context(), db, fileUUID))
+
+	snapshotRevision, err := snapshotRevisionOf(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), snapshotRevision)
+	require.Equal(t, int64(3), countRows[models.DocumentOperation](t, fileUUID))
+	require.Equal(t, int64(1), countRows[models.FilesContents](t, fileUUID))
+
+	// CASE undo of a delete whose tombstone was removed
+	operation, err := Undo(t.Context(), db, author, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, OperationInsert, operation.OperationType)
+	require.Equal(t, "ab", visibleText(t, fileUUID))
+
+	// CASE the operations are removed once the session is gone
+	redisUtils.DeleteSessionInRedis(author.SessionID, t.Context())
+	ageOperations(t, fileUUID)
+	require.NoError(t, CompactDocument(t.Context(), db, fileUUID))
+	require.Equal(t, int64(0), countRows[models.DocumentOperation](t, fileUUID))
+}
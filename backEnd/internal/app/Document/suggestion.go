@@ -0,0 +1,259 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Websocket events of the suggestions, sent to the sessions of the file
+const (
+	SuggestionCreated  = "suggestion_created"
+	SuggestionAccepted = "suggestion_accepted"
+	SuggestionRejected = "suggestion_rejected"
+)
+
+var (
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrSuggestionPending  = errors.New("a suggestion is already pending on this character")
+	ErrSuggestionRole     = errors.New("role doesn't allow to resolve this suggestion")
+)
+
+// Suggestion is a pending, accepted or rejected insert or delete of one character.
+type Suggestion struct {
+	SuggestionID string               `json:"suggestion_id"`
+	FileUUID     string               `json:"file_uuid"`
+	Kind         string               `json:"kind"`
+	Character    common.CharacterData `json:"character"`
+	UserID       uint32               `json:"user_id"`
+	Status       string               `json:"status"`
+	ResolvedBy   uint32               `json:"resolved_by"`
+	CreatedAt    int64                `json:"created_at"`
+}
+
+// SuggestInsert records the insert of the character as a suggestion of the author,
+// the content of the file is left untouched until it is accepted.
+func SuggestInsert(ctx context.Context, db *gorm.DB, author Author, fileUUID string, char common.CharacterData) (Suggestion, error) {
+	if !validCharacter(char) {
+		return Suggestion{}, ErrInvalidCharacter
+	}
+	if char.Value == Newline && char.Block != "" {
+		err := models.ValidateBlock(char.Block, char.Level, char.Language)
+		if err != nil {
+			return Suggestion{}, err
+		}
+	}
+	return createSuggestion(ctx, db, author, fileUUID, OperationInsert, char)
+}
+
+// SuggestDelete records the delete of the visible character at path as a suggestion of the author.
+// Deleting a character the author suggested to insert withdraws the suggestion instead.
+func SuggestDelete(ctx context.Context, db *gorm.DB, author Author, fileUUID string, path []int) (Suggestion, error) {
+	if !validPath(path) {
+		return Suggestion{}, ErrInvalidCharacter
+	}
+
+	var suggestion Suggestion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		own, err := gorm.G[models.Suggestion](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("file_uuid = ?", fileUUID).
+			Where("char_path = ?", convertUtils.SliceIntToByte(path)).
+			Where("kind = ?", OperationInsert).
+			Where("user_id = ?", author.UserID).
+			Where("status = ?", models.SuggestionPending).
+			First(ctx)
+		if err == nil {
+			suggestion, err = resolveSuggestion(ctx, tx, own, author.UserID, models.SuggestionRejected)
+			return err
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		char, err := gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("char_path = ?", convertUtils.SliceIntToByte(path)).
+			Where("deleted = ?", false).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCharacterNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		suggestion, err = createSuggestion(ctx, tx, author, fileUUID, OperationDelete, toCharacterData(char))
+		return err
+	})
+	return suggestion, err
+}
+
+func createSuggestion(ctx context.Context, db *gorm.DB, author Author, fileUUID string, kind string, char common.CharacterData) (Suggestion, error) {
+	payload, err := json.Marshal(char)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	path := convertUtils.SliceIntToByte(char.Path)
+	nbPending, err := gorm.G[models.Suggestion](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("char_path = ?", path).
+		Where("status = ?", models.SuggestionPending).
+		Count(ctx, "suggestion_id")
+	if err != nil {
+		return Suggestion{}, err
+	}
+	if nbPending > 0 {
+		return Suggestion{}, ErrSuggestionPending
+	}
+
+	row := models.Suggestion{
+		FileUUID:  fileUUID,
+		Path:      path,
+		Kind:      kind,
+		Payload:   string(payload),
+		UserID:    author.UserID,
+		SessionID: author.SessionID,
+		Status:    models.SuggestionPending,
+		CreatedAt: time.Now().Unix(),
+	}
+	err = gorm.G[models.Suggestion](db).Create(ctx, &row)
+	if err != nil {
+		return Suggestion{}, err
+	}
+	return toSuggestion(row), nil
+}
+
+// ListSuggestions returns the pending suggestions of the file, oldest first.
+func ListSuggestions(ctx context.Context, db *gorm.DB, fileUUID string) ([]Suggestion, error) {
+	rows, err := gorm.G[models.Suggestion](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("status = ?", models.SuggestionPending).
+		Order("created_at").
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(rows))
+	for _, row := range rows {
+		suggestions = append(suggestions, toSuggestion(row))
+	}
+	return suggestions, nil
+}
+
+// AcceptSuggestion applies the suggestion to the file as an operation of the actor, who must
+// be allowed to edit. The operation is nil when the character was already deleted by someone else.
+func AcceptSuggestion(ctx context.Context, db *gorm.DB, actor Author, role string, fileUUID string, suggestionID string) (Suggestion, *common.DocumentOperation, error) {
+	if !accessUtils.CanEdit(role) {
+		return Suggestion{}, nil, ErrSuggestionRole
+	}
+
+	var suggestion Suggestion
+	var operation *common.DocumentOperation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		row, err := pendingSuggestion(ctx, tx, fileUUID, suggestionID)
+		if err != nil {
+			return err
+		}
+		char := toSuggestion(row).Character
+
+		var revision int64
+		switch row.Kind {
+		case OperationInsert:
+			revision, err = InsertCharacter(ctx, tx, actor, fileUUID, char)
+		case OperationDelete:
+			revision, err = DeleteCharacter(ctx, tx, actor, fileUUID, char.Path)
+		}
+		if errors.Is(err, ErrCharacterNotFound) {
+			err = nil
+		} else if err == nil {
+			operation = &common.DocumentOperation{
+				OperationType: row.Kind,
+				FileUUID:      fileUUID,
+				SessionID:     actor.SessionID,
+				UserID:        actor.UserID,
+				Revision:      revision,
+				Data:          char,
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		suggestion, err = resolveSuggestion(ctx, tx, row, actor.UserID, models.SuggestionAccepted)
+		return err
+	})
+	return suggestion, operation, err
+}
+
+// RejectSuggestion discards the suggestion. Editors reject any suggestion and the
+// other users their own ones.
+func RejectSuggestion(ctx context.Context, db *gorm.DB, actor Author, role string, fileUUID string, suggestionID string) (Suggestion, error) {
+	var suggestion Suggestion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		row, err := pendingSuggestion(ctx, tx, fileUUID, suggestionID)
+		if err != nil {
+			return err
+		}
+		if !accessUtils.CanEdit(role) && row.UserID != actor.UserID {
+			return ErrSuggestionRole
+		}
+
+		suggestion, err = resolveSuggestion(ctx, tx, row, actor.UserID, models.SuggestionRejected)
+		return err
+	})
+	return suggestion, err
+}
+
+// pendingSuggestion locks the pending suggestion so it is resolved only once.
+func pendingSuggestion(ctx context.Context, tx *gorm.DB, fileUUID string, suggestionID string) (models.Suggestion, error) {
+	row, err := gorm.G[models.Suggestion](tx, clause.Locking{Strength: "UPDATE"}).
+		Where("suggestion_id = ?", suggestionID).
+		Where("file_uuid = ?", fileUUID).
+		Where("status = ?", models.SuggestionPending).
+		First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return row, ErrSuggestionNotFound
+	}
+	return row, err
+}
+
+func resolveSuggestion(ctx context.Context, tx *gorm.DB, row models.Suggestion, userID uint32, status string) (Suggestion, error) {
+	row.Status = status
+	row.ResolvedBy = userID
+	row.ResolvedAt = time.Now().Unix()
+
+	err := tx.WithContext(ctx).Model(&models.Suggestion{}).
+		Where("suggestion_id = ?", row.SuggestionID).
+		Updates(map[string]interface{}{
+			"status":      row.Status,
+			"resolved_by": row.ResolvedBy,
+			"resolved_at": row.ResolvedAt,
+		}).Error
+	return toSuggestion(row), err
+}
+
+func toSuggestion(row models.Suggestion) Suggestion {
+	var char common.CharacterData
+	json.Unmarshal([]byte(row.Payload), &char)
+
+	return Suggestion{
+		SuggestionID: row.SuggestionID,
+		FileUUID:     row.FileUUID,
+		Kind:         row.Kind,
+		Character:    char,
+		UserID:       row.UserID,
+		Status:       row.Status,
+		ResolvedBy:   row.ResolvedBy,
+		CreatedAt:    row.CreatedAt,
+	}
+}
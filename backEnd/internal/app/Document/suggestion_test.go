@@ -0,0 +1,150 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptSuggestion(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	editor := Author{UserID: insertUser(t, "editor"), SessionID: "editor-session"}
+	commenter := Author{UserID: insertUser(t, "commenter"), SessionID: "commenter-session"}
+	insertText(t, editor, fileUUID, "ac")
+
+	// CASE accept of an insert, the character is added as an operation of the editor
+	insert, err := SuggestInsert(t.Context(), db, commenter, fileUUID, common.CharacterData{Value: "b", Path: []int{1, 5}})
+	require.NoError(t, err)
+	require.Equal(t, models.SuggestionPending, insert.Status)
+	require.Equal(t, "ac", visibleText(t, fileUUID))
+
+	accepted, operation, err := AcceptSuggestion(t.Context(), db, editor, models.RoleCollaborator, fileUUID, insert.SuggestionID)
+	require.NoError(t, err)
+	require.Equal(t, models.SuggestionAccepted, accepted.Status)
+	require.Equal(t, editor.UserID, accepted.ResolvedBy)
+	require.NotNil(t, operation)
+	require.Equal(t, OperationInsert, operation.OperationType)
+	require.Equal(t, editor.UserID, operation.UserID)
+	require.Equal(t, "abc", visibleText(t, fileUUID))
+
+	// CASE accept of a delete
+	deletion, err := SuggestDelete(t.Context(), db, commenter, fileUUID, []int{2, 0})
+	require.NoError(t, err)
+	require.Equal(t, OperationDelete, deletion.Kind)
+	require.Equal(t, "abc", visibleText(t, fileUUID))
+
+	_, operation, err = AcceptSuggestion(t.Context(), db, editor, models.RoleOwner, fileUUID, deletion.SuggestionID)
+	require.NoError(t, err)
+	require.NotNil(t, operation)
+	require.Equal(t, OperationDelete, operation.OperationType)
+	require.Equal(t, "ab", visibleText(t, fileUUID))
+
+	// CASE a resolved suggestion can't be accepted again
+	_, _, err = AcceptSuggestion(t.Context(), db, editor, models.RoleOwner, fileUUID, deletion.SuggestionID)
+	require.ErrorIs(t, err, ErrSuggestionNotFound)
+
+	// CASE the character was deleted in the meantime, accepted without operation
+	deletion, err = SuggestDelete(t.Context(), db, commenter, fileUUID, []int{1, 0})
+	require.NoError(t, err)
+	_, err = DeleteCharacter(t.Context(), db, editor, fileUUID, []int{1, 0})
+	require.NoError(t, err)
+	accepted, operation, err = AcceptSuggestion(t.Context(), db, editor, models.RoleOwner, fileUUID, deletion.SuggestionID)
+	require.NoError(t, err)
+	require.Nil(t, operation)
+	require.Equal(t, models.SuggestionAccepted, accepted.Status)
+
+	// CASE only the editors accept
+	insert, err = SuggestInsert(t.Context(), db, commenter, fileUUID, common.CharacterData{Value: "d", Path: []int{4, 0}})
+	require.NoError(t, err)
+	_, _, err = AcceptSuggestion(t.Context(), db, commenter, models.RoleCommenter, fileUUID, insert.SuggestionID)
+	require.ErrorIs(t, err, ErrSuggestionRole)
+
+	// CASE the suggestion belongs to another file
+	_, _, err = AcceptSuggestion(t.Context(), db, editor, models.RoleOwner, "22222222-2222-2222-2222-222222222222", insert.SuggestionID)
+	require.ErrorIs(t, err, ErrSuggestionNotFound)
+}
+
+func TestRejectSuggestion(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	editor := Author{UserID: insertUser(t, "editor"), SessionID: "editor-session"}
+	author := Author{UserID: insertUser(t, "author"), SessionID: "author-session"}
+	other := Author{UserID: insertUser(t, "other"), SessionID: "other-session"}
+	insertText(t, editor, fileUUID, "ab")
+
+	// CASE another commenter can't reject the suggestion
+	suggestion, err := SuggestDelete(t.Context(), db, author, fileUUID, []int{1, 0})
+	require.NoError(t, err)
+	_, err = RejectSuggestion(t.Context(), db, other, models.RoleCommenter, fileUUID, suggestion.SuggestionID)
+	require.ErrorIs(t, err, ErrSuggestionRole)
+
+	// CASE the author rejects their own suggestion
+	rejected, err := RejectSuggestion(t.Context(), db, author, models.RoleCommenter, fileUUID, suggestion.SuggestionID)
+	require.NoError(t, err)
+	require.Equal(t, models.SuggestionRejected, rejected.Status)
+	require.Equal(t, author.UserID, rejected.ResolvedBy)
+	require.Equal(t, "ab", visibleText(t, fileUUID))
+
+	// CASE an editor rejects the suggestion of someone else
+	suggestion, err = SuggestInsert(t.Context(), db, author, fileUUID, common.CharacterData{Value: "c", Path: []int{3, 0}})
+	require.NoError(t, err)
+	rejected, err = RejectSuggestion(t.Context(), db, editor, models.RoleCollaborator, fileUUID, suggestion.SuggestionID)
+	require.NoError(t, err)
+	require.Equal(t, models.SuggestionRejected, rejected.Status)
+	require.Equal(t, editor.UserID, rejected.ResolvedBy)
+	require.Equal(t, "ab", visibleText(t, fileUUID))
+
+	suggestions, err := ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Empty(t, suggestions)
+}
+
+func TestSuggestDelete(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	editor := Author{UserID: insertUser(t, "editor"), SessionID: "editor-session"}
+	author := Author{UserID: insertUser(t, "author"), SessionID: "author-session"}
+	other := Author{UserID: insertUser(t, "other"), SessionID: "other-session"}
+	insertText(t, editor, fileUUID, "ab")
+
+	// CASE deleting an own suggested insert withdraws it
+	insert, err := SuggestInsert(t.Context(), db, author, fileUUID, common.CharacterData{Value: "c", Path: []int{3, 0}})
+	require.NoError(t, err)
+	withdrawn, err := SuggestDelete(t.Context(), db, author, fileUUID, []int{3, 0})
+	require.NoError(t, err)
+	require.Equal(t, insert.SuggestionID, withdrawn.SuggestionID)
+	require.Equal(t, models.SuggestionRejected, withdrawn.Status)
+
+	suggestions, err := ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Empty(t, suggestions)
+
+	// CASE a suggestion is already pending on the character
+	_, err = SuggestInsert(t.Context(), db, author, fileUUID, common.CharacterData{Value: "c", Path: []int{3, 0}})
+	require.NoError(t, err)
+	_, err = SuggestInsert(t.Context(), db, other, fileUUID, common.CharacterData{Value: "d", Path: []int{3, 0}})
+	require.ErrorIs(t, err, ErrSuggestionPending)
+
+	_, err = SuggestDelete(t.Context(), db, author, fileUUID, []int{1, 0})
+	require.NoError(t, err)
+	_, err = SuggestDelete(t.Context(), db, other, fileUUID, []int{1, 0})
+	require.ErrorIs(t, err, ErrSuggestionPending)
+
+	// CASE the insert suggested by someone else is not a visible character yet
+	_, err = SuggestDelete(t.Context(), db, other, fileUUID, []int{3, 0})
+	require.ErrorIs(t, err, ErrCharacterNotFound)
+
+	suggestions, err = ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+}
@@ -0,0 +1,17 @@
+package document
+
+import "bufio"
+
+// textRenderer writes the text of the document without any formatting, one line per block.
+type textRenderer struct{}
+
+func (textRenderer) open(out *bufio.Writer) {}
+
+func (textRenderer) writeBlock(out *bufio.Writer, current block) {
+	for _, char := range current.Characters {
+		out.WriteString(char.Value)
+	}
+	out.WriteString("\n")
+}
+
+func (textRenderer) close(out *bufio.Writer) {}
@@ -0,0 +1,348 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of logged operations
+const (
+	OperationKindEdit = "edit"
+	OperationKindUndo = "undo"
+	OperationKindRedo = "redo"
+)
+
+var (
+	ErrNothingToUndo = errors.New("no operation to undo")
+	ErrNothingToRedo = errors.New("no operation to redo")
+)
+
+var editOperations = []string{OperationInsert, OperationDelete, OperationFormat, OperationBlock}
+
+// Undo reverts the last operation of the author that is not undone yet. Only the operations
+// of this user and session are considered, the ones of the other collaborators are kept.
+// Return the inverse operation, recorded and ready to be broadcast.
+func Undo(ctx context.Context, db *gorm.DB, author Author, fileUUID string) (common.DocumentOperation, error) {
+	var operation common.DocumentOperation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		target, err := gorm.G[models.DocumentOperation](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("file_uuid = ?", fileUUID).
+			Where("user_id = ?", author.UserID).
+			Where("session_id = ?", author.SessionID).
+			Where("operation_type IN ?", editOperations).
+			Where("kind IN ?", []string{OperationKindEdit, OperationKindRedo}).
+			Where("undone = ?", false).
+			Order("revision desc").
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+
+		operation, err = revertOperation(ctx, tx, author, target, OperationKindUndo)
+		return err
+	})
+	return operation, err
+}
+
+// Redo reverts the last undo of the author, as long as the author made no edit since.
+// Return the operation, recorded and ready to be broadcast.
+func Redo(ctx context.Context, db *gorm.DB, author Author, fileUUID string) (common.DocumentOperation, error) {
+	var operation common.DocumentOperation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		target, err := lastRedoableUndo(ctx, tx, author, fileUUID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNothingToRedo
+		}
+		if err != nil {
+			return err
+		}
+
+		operation, err = revertOperation(ctx, tx, author, target, OperationKindRedo)
+		return err
+	})
+	return operation, err
+}
+
+// lastRedoableUndo finds the most recent undo of the author that is not redone yet.
+// The redo history is lost once the author edits again.
+func lastRedoableUndo(ctx context.Context, tx *gorm.DB, author Author, fileUUID string) (models.DocumentOperation, error) {
+	return gorm.G[models.DocumentOperation](tx, clause.Locking{Strength: "UPDATE"}).
+		Where("file_uuid = ?", fileUUID).
+		Where("user_id = ?", author.UserID).
+		Where("session_id = ?", author.SessionID).
+		Where("kind = ?", OperationKindUndo).
+		Where("undone = ?", false).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM document_operations edit
+			WHERE edit.file_uuid = document_operations.file_uuid
+			AND edit.session_id = document_operations.session_id
+			AND edit.kind = ?
+			AND edit.revision > document_operations.revision
+		)`, OperationKindEdit).
+		Order("revision desc").
+		First(ctx)
+}
+
+// revertOperation applies the inverse of target against the current state of the document,
+// records it with the given kind and marks target as undone.
+func revertOperation(ctx context.Context, tx *gorm.DB, author Author, target models.DocumentOperation, kind string) (common.DocumentOperation, error) {
+	var operation common.DocumentOperation
+
+	revision, err := nextRevision(ctx, tx, target.FileUUID)
+	if err != nil {
+		return operation, err
+	}
+
+	site := lseqUtils.SiteFromSession(author.SessionID)
+	var operationType string
+	var data interface{}
+
+	switch target.OperationType {
+	case OperationInsert:
+		var char common.CharacterData
+		err = json.Unmarshal([]byte(target.Payload), &char)
+		if err == nil {
+			operationType, data = OperationDelete, char
+			err = tombstoneCharacter(ctx, tx, target.FileUUID, char.Path, revision)
+		}
+	case OperationDelete:
+		var char common.CharacterData
+		err = json.Unmarshal([]byte(target.Payload), &char)
+		if err == nil {
+			operationType, data = OperationInsert, char
+			err = reviveCharacter(ctx, tx, target.FileUUID, char, revision)
+		}
+	case OperationFormat:
+		var format common.FormatData
+		err = json.Unmarshal([]byte(target.Payload), &format)
+		if err == nil {
+			operationType = OperationFormat
+			data, err = revertFormat(ctx, tx, target.FileUUID, format, site)
+		}
+	case OperationBlock:
+		var block common.BlockData
+		err = json.Unmarshal([]byte(target.Payload), &block)
+		if err == nil {
+			operationType = OperationBlock
+			data, err = revertBlock(ctx, tx, target.FileUUID, block, site)
+		}
+	default:
+		return operation, ErrNothingToUndo
+	}
+	if err != nil {
+		return operation, err
+	}
+
+	err = recordOperationKind(ctx, tx, author, target.FileUUID, revision, operationType, data, kind, target.Revision)
+	if err != nil {
+		return operation, err
+	}
+
+	_, err = gorm.G[models.DocumentOperation](tx).
+		Where("operation_id = ?", target.OperationID).
+		Update(ctx, "undone", true)
+	if err != nil {
+		return operation, err
+	}
+
+	operation = common.DocumentOperation{
+		OperationType: operationType,
+		FileUUID:      target.FileUUID,
+		SessionID:     author.SessionID,
+		UserID:        author.UserID,
+		Revision:      revision,
+		Data:          data,
+	}
+	return operation, nil
+}
+
+// tombstoneCharacter deletes the character if it is still visible.
+func tombstoneCharacter(ctx context.Context, tx *gorm.DB, fileUUID string, path []int, revision int64) error {
+	_, err := gorm.G[models.FilesContents](tx).
+		Where("file_uuid = ?", fileUUID).
+		Where("char_path = ?", convertUtils.SliceIntToByte(path)).
+		Where("deleted = ?", false).
+		Updates(ctx, models.FilesContents{Deleted: true, DeletedRev: revision})
+	return err
+}
+
+// reviveCharacter makes a deleted character visible again at the same position,
+// the row is created again if it was already garbage-collected.
+func reviveCharacter(ctx context.Context, tx *gorm.DB, fileUUID string, char common.CharacterData, revision int64) error {
+	result := tx.WithContext(ctx).Model(&models.FilesContents{}).
+		Where("file_uuid = ?", fileUUID).
+		Where("char_path = ?", convertUtils.SliceIntToByte(char.Path)).
+		Updates(map[string]interface{}{
+			"deleted":          false,
+			"deleted_revision": 0,
+		})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	block, level, language := models.BlockParagraph, 0, ""
+	if char.Value == Newline && char.Block != "" {
+		block, level, language = char.Block, char.Level, char.Language
+	}
+
+	return gorm.G[models.FilesContents](tx).Create(ctx, &models.FilesContents{
+		CharacterValue: []byte(char.Value),
+		Path:           convertUtils.SliceIntToByte(char.Path),
+		SortKey:        lseqUtils.SortKey(lseqUtils.FromInts(char.Path)),
+		Style:          char.Style,
+		Color:          char.Color,
+		InsertedRev:    revision,
+		StyleClock:     "{}",
+		Block:          block,
+		BlockLevel:     level,
+		BlockLanguage:  language,
+		FileUUID:       fileUUID,
+	})
+}
+
+// revertFormat gives back to the characters the attributes they had before the format,
+// except the ones another collaborator changed since.
+func revertFormat(ctx context.Context, tx *gorm.DB, fileUUID string, format common.FormatData, site int) (common.FormatData, error) {
+	reverted := common.FormatData{
+		From:      format.From,
+		To:        format.To,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	for i, result := range format.Characters {
+		if i >= len(format.Previous) {
+			break
+		}
+		previous := format.Previous[i]
+
+		char, err := gorm.G[models.FilesContents](tx).
+			Where("file_uuid = ?", fileUUID).
+			Where("char_path = ?", convertUtils.SliceIntToByte(result.Path)).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return reverted, err
+		}
+
+		var revert common.FormatData
+		for bit := range styleAttributes {
+			changedByFormat := (result.Style^previous.Style)&bit != 0
+			unchangedSince := (char.Style^result.Style)&bit == 0
+			if !changedByFormat || !unchangedSince {
+				continue
+			}
+			if previous.Style&bit != 0 {
+				revert.Set |= bit
+			} else {
+				revert.Clear |= bit
+			}
+		}
+		if result.Color != previous.Color && char.Color == result.Color {
+			revert.Color = &previous.Color
+		}
+		if revert.Set|revert.Clear == 0 && revert.Color == nil {
+			continue
+		}
+
+		before := toCharacterData(char)
+		changed, err := applyFormat(&char, revert, revertClock(char.StyleClock, reverted.Timestamp, site))
+		if err != nil {
+			return reverted, err
+		}
+		if !changed {
+			continue
+		}
+
+		err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+			Where("content_id = ?", char.ContentsID).
+			Updates(map[string]interface{}{
+				"char_style":  char.Style,
+				"color":       char.Color,
+				"style_clock": char.StyleClock,
+			}).Error
+		if err != nil {
+			return reverted, err
+		}
+
+		reverted.Previous = append(reverted.Previous, before)
+		reverted.Characters = append(reverted.Characters, toCharacterData(char))
+	}
+	return reverted, nil
+}
+
+// revertBlock gives back to the newline the block it had before, unless another
+// collaborator changed it since.
+func revertBlock(ctx context.Context, tx *gorm.DB, fileUUID string, block common.BlockData, site int) (common.BlockData, error) {
+	char, err := gorm.G[models.FilesContents](tx).
+		Where("file_uuid = ?", fileUUID).
+		Where("char_path = ?", convertUtils.SliceIntToByte(block.Path)).
+		First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return block, ErrCharacterNotFound
+	}
+	if err != nil {
+		return block, err
+	}
+
+	current := toCharacterData(char)
+	reverted := common.BlockData{
+		Path:      block.Path,
+		Block:     current.Block,
+		Level:     current.Level,
+		Language:  current.Language,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	unchangedSince := current.Block == block.Block && current.Level == block.Level && current.Language == block.Language
+	if block.Previous == nil || !unchangedSince {
+		return reverted, nil
+	}
+
+	reverted.Block = block.Previous.Block
+	reverted.Level = block.Previous.Level
+	reverted.Language = block.Previous.Language
+
+	changed, err := applyBlock(&char, reverted, revertClock(char.StyleClock, reverted.Timestamp, site))
+	if err != nil || !changed {
+		return reverted, err
+	}
+	reverted.Previous = &current
+
+	err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+		Where("content_id = ?", char.ContentsID).
+		Updates(map[string]interface{}{
+			"block_type":     char.Block,
+			"block_level":    char.BlockLevel,
+			"block_language": char.BlockLanguage,
+			"style_clock":    char.StyleClock,
+		}).Error
+	return reverted, err
+}
+
+// revertClock returns a clock newer than every attribute clock of the character,
+// a revert must win even against a format stamped by a client ahead of the server time.
+func revertClock(styleClock string, timestamp int64, site int) AttributeClock {
+	clocks := map[string]AttributeClock{}
+	json.Unmarshal([]byte(styleClock), &clocks)
+
+	for _, clock := range clocks {
+		if clock.Timestamp >= timestamp {
+			timestamp = clock.Timestamp + 1
+		}
+	}
+	return AttributeClock{Timestamp: timestamp, Site: site}
+}
@@ -0,0 +1,103 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoRedo(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	first := Author{UserID: insertUser(t, "first"), SessionID: "first-session"}
+	second := Author{UserID: insertUser(t, "second"), SessionID: "second-session"}
+
+	insertText(t, first, fileUUID, "abc")
+	_, err := InsertCharacter(t.Context(), db, second, fileUUID, common.CharacterData{Value: "d", Path: []int{4, 0}})
+	require.NoError(t, err)
+
+	// CASE nothing to undo or redo
+	_, err = Undo(t.Context(), db, Author{UserID: insertUser(t, "third"), SessionID: "third-session"}, fileUUID)
+	require.ErrorIs(t, err, ErrNothingToUndo)
+	_, err = Redo(t.Context(), db, first, fileUUID)
+	require.ErrorIs(t, err, ErrNothingToRedo)
+
+	// CASE the undo reverts the last own operation, not the later one of another user
+	operation, err := Undo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, OperationDelete, operation.OperationType)
+	require.Equal(t, int64(5), operation.Revision)
+	require.Equal(t, first.SessionID, operation.SessionID)
+	require.Equal(t, []int{3, 0}, operation.Data.(common.CharacterData).Path)
+	require.Equal(t, "abd", visibleText(t, fileUUID))
+
+	operation, err = Undo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 0}, operation.Data.(common.CharacterData).Path)
+	require.Equal(t, "ad", visibleText(t, fileUUID))
+
+	// CASE another session of the same user has its own history
+	_, err = Undo(t.Context(), db, Author{UserID: first.UserID, SessionID: "first-other-session"}, fileUUID)
+	require.ErrorIs(t, err, ErrNothingToUndo)
+
+	// CASE the redo reverts the last undo, and can itself be undone
+	operation, err = Redo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, OperationInsert, operation.OperationType)
+	require.Equal(t, "abd", visibleText(t, fileUUID))
+
+	_, err = Undo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, "ad", visibleText(t, fileUUID))
+	_, err = Redo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, "abd", visibleText(t, fileUUID))
+
+	// CASE a new edit drops the redo history
+	_, err = InsertCharacter(t.Context(), db, first, fileUUID, common.CharacterData{Value: "e", Path: []int{5, 0}})
+	require.NoError(t, err)
+	_, err = Redo(t.Context(), db, first, fileUUID)
+	require.ErrorIs(t, err, ErrNothingToRedo)
+
+	// CASE undoing a delete brings the character back with its attributes
+	_, err = InsertCharacter(t.Context(), db, first, fileUUID, common.CharacterData{Value: "f", Path: []int{6, 0}, Style: models.StyleItalic, Color: "#0000ff"})
+	require.NoError(t, err)
+	_, err = DeleteCharacter(t.Context(), db, first, fileUUID, []int{6, 0})
+	require.NoError(t, err)
+	require.Equal(t, "abde", visibleText(t, fileUUID))
+
+	operation, err = Undo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, OperationInsert, operation.OperationType)
+	content, err := LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, common.CharacterData{Value: "f", Path: []int{6, 0}, Style: models.StyleItalic, Color: "#0000ff"}, content.Characters[len(content.Characters)-1])
+
+	// CASE undoing a format gives the characters their previous style back
+	_, _, err = FormatRange(t.Context(), db, first, fileUUID, common.FormatData{From: []int{1, 0}, To: []int{2, 0}, Set: models.StyleBold})
+	require.NoError(t, err)
+	content, err = LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, models.StyleBold, content.Characters[0].Style)
+	require.Equal(t, models.StyleBold, content.Characters[1].Style)
+
+	operation, err = Undo(t.Context(), db, first, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, OperationFormat, operation.OperationType)
+	content, err = LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), content.Characters[0].Style)
+	require.Equal(t, uint32(0), content.Characters[1].Style)
+
+	// CASE the undo and redo are logged like edits, the other user's operation untouched
+	operations, err := OperationsSince(t.Context(), db, fileUUID, 0)
+	require.NoError(t, err)
+	require.Len(t, operations, 15)
+	require.Equal(t, second.SessionID, operations[3].SessionID)
+	require.Equal(t, "abdef", visibleText(t, fileUUID))
+}
@@ -0,0 +1,317 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrVersionNotFound    = errors.New("version not found")
+	ErrInvalidVersionID   = errors.New("version ID must be a UUID")
+	ErrInvalidVersionName = errors.New("version name must be between 1 and 100 characters")
+)
+
+const allStyles = models.StyleBold | models.StyleItalic | models.StyleUnderline | models.StyleStrike | models.StyleCode
+
+// Version is a named checkpoint of a file with its content.
+type Version struct {
+	models.FileVersion
+	Characters []common.CharacterData `json:"characters"`
+}
+
+// CreateVersion saves the current content of the file as a named checkpoint.
+func CreateVersion(ctx context.Context, db *gorm.DB, userID uint32, fileUUID string, name string) (models.FileVersion, error) {
+	var version models.FileVersion
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return version, ErrInvalidVersionName
+	}
+
+	content, err := LoadDocument(ctx, db, fileUUID)
+	if err != nil {
+		return version, err
+	}
+
+	bContent, err := json.Marshal(content.Characters)
+	if err != nil {
+		return version, err
+	}
+
+	version = models.FileVersion{
+		FileUUID:  fileUUID,
+		Name:      name,
+		Revision:  content.Revision,
+		CreatedBy: userID,
+		CreatedAt: time.Now().Unix(),
+		Content:   string(bContent),
+	}
+	err = gorm.G[models.FileVersion](db).Create(ctx, &version)
+	return version, err
+}
+
+// ListVersions returns the checkpoints of the file, most recent first, without their content.
+func ListVersions(ctx context.Context, db *gorm.DB, fileUUID string) ([]models.FileVersion, error) {
+	return gorm.G[models.FileVersion](db).
+		Select("version_id", "file_uuid", "name", "revision", "created_by", "created_at").
+		Where("file_uuid = ?", fileUUID).
+		Order("created_at desc").
+		Find(ctx)
+}
+
+// GetVersion returns a checkpoint with its content, in document order.
+func GetVersion(ctx context.Context, db *gorm.DB, versionID string) (Version, error) {
+	version := Version{Characters: []common.CharacterData{}}
+	if uuid.Validate(versionID) != nil {
+		return version, ErrInvalidVersionID
+	}
+
+	fileVersion, err := gorm.G[models.FileVersion](db).Where("version_id = ?", versionID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return version, ErrVersionNotFound
+	}
+	if err != nil {
+		return version, err
+	}
+	version.FileVersion = fileVersion
+
+	err = json.Unmarshal([]byte(fileVersion.Content), &version.Characters)
+	return version, err
+}
+
+// RestoreVersion brings the file back to the content of the checkpoint. Instead of replacing
+// the content, it generates the operations that lead to it: characters added since are deleted,
+// characters deleted since are inserted again and the ones restyled get their style back.
+// Live collaborators converge by applying them like any other edit.
+// Return the recorded operations, in revision order.
+func RestoreVersion(ctx context.Context, db *gorm.DB, author Author, versionID string) ([]common.DocumentOperation, error) {
+	version, err := GetVersion(ctx, db, versionID)
+	if err != nil {
+		return nil, err
+	}
+	fileUUID := version.FileUUID
+	site := lseqUtils.SiteFromSession(author.SessionID)
+
+	var operations []common.DocumentOperation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// lock the file so no operation interleaves with the restore
+		_, err := gorm.G[models.File](tx, clause.Locking{Strength: "UPDATE"}).Where("file_uuid = ?", fileUUID).First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		chars, err := gorm.G[models.FilesContents](tx).Where("file_uuid = ?", fileUUID).Find(ctx)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[string]models.FilesContents, len(chars))
+		for _, char := range chars {
+			current[string(char.Path)] = char
+		}
+
+		wanted := make(map[string]bool, len(version.Characters))
+		for _, target := range version.Characters {
+			wanted[string(convertUtils.SliceIntToByte(target.Path))] = true
+		}
+
+		record := func(revision int64, operationType string, data interface{}) error {
+			err := recordOperation(ctx, tx, author, fileUUID, revision, operationType, data)
+			if err != nil {
+				return err
+			}
+			operations = append(operations, common.DocumentOperation{
+				OperationType: operationType,
+				FileUUID:      fileUUID,
+				SessionID:     author.SessionID,
+				UserID:        author.UserID,
+				Revision:      revision,
+				Data:          data,
+			})
+			return nil
+		}
+
+		sortCharacters(chars)
+		for _, char := range chars {
+			if char.Deleted || wanted[string(char.Path)] {
+				continue
+			}
+			revision, err := nextRevision(ctx, tx, fileUUID)
+			if err != nil {
+				return err
+			}
+			err = tombstoneCharacter(ctx, tx, fileUUID, convertUtils.SliceByteToSliceInt(char.Path), revision)
+			if err != nil {
+				return err
+			}
+			err = record(revision, OperationDelete, toCharacterData(char))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, target := range version.Characters {
+			char, exists := current[string(convertUtils.SliceIntToByte(target.Path))]
+
+			if !exists || char.Deleted {
+				revision, err := nextRevision(ctx, tx, fileUUID)
+				if err != nil {
+					return err
+				}
+				err = restoreCharacter(ctx, tx, fileUUID, char, exists, target, revision, site)
+				if err != nil {
+					return err
+				}
+				err = record(revision, OperationInsert, target)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			if char.Style != target.Style || char.Color != target.Color {
+				format, err := restoreStyle(ctx, tx, &char, target, site)
+				if err != nil {
+					return err
+				}
+				revision, err := nextRevision(ctx, tx, fileUUID)
+				if err != nil {
+					return err
+				}
+				err = record(revision, OperationFormat, format)
+				if err != nil {
+					return err
+				}
+			}
+
+			if target.Value == Newline && (char.Block != target.Block || char.BlockLevel != target.Level || char.BlockLanguage != target.Language) {
+				block, err := restoreBlock(ctx, tx, char, target, site)
+				if err != nil {
+					return err
+				}
+				revision, err := nextRevision(ctx, tx, fileUUID)
+				if err != nil {
+					return err
+				}
+				err = record(revision, OperationBlock, block)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// restoreCharacter makes a character of the version visible again with the attributes it had.
+func restoreCharacter(ctx context.Context, tx *gorm.DB, fileUUID string, char models.FilesContents, exists bool, target common.CharacterData, revision int64, site int) error {
+	if !exists {
+		return reviveCharacter(ctx, tx, fileUUID, target, revision)
+	}
+
+	block, level, language := models.BlockParagraph, 0, ""
+	if target.Value == Newline && target.Block != "" {
+		block, level, language = target.Block, target.Level, target.Language
+	}
+
+	return tx.WithContext(ctx).Model(&models.FilesContents{}).
+		Where("content_id = ?", char.ContentsID).
+		Updates(map[string]interface{}{
+			"deleted":          false,
+			"deleted_revision": 0,
+			"char_style":       target.Style,
+			"color":            target.Color,
+			"block_type":       block,
+			"block_level":      level,
+			"block_language":   language,
+			"style_clock":      forcedClocks(char.StyleClock, site),
+		}).Error
+}
+
+// restoreStyle sets the style and color of the version on a visible character.
+func restoreStyle(ctx context.Context, tx *gorm.DB, char *models.FilesContents, target common.CharacterData, site int) (common.FormatData, error) {
+	color := target.Color
+	format := common.FormatData{
+		From:      target.Path,
+		To:        target.Path,
+		Set:       target.Style,
+		Clear:     allStyles &^ target.Style,
+		Color:     &color,
+		Timestamp: time.Now().UnixMilli(),
+		Previous:  []common.CharacterData{toCharacterData(*char)},
+	}
+
+	_, err := applyFormat(char, format, revertClock(char.StyleClock, format.Timestamp, site))
+	if err != nil {
+		return format, err
+	}
+	format.Characters = []common.CharacterData{toCharacterData(*char)}
+
+	err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+		Where("content_id = ?", char.ContentsID).
+		Updates(map[string]interface{}{
+			"char_style":  char.Style,
+			"color":       char.Color,
+			"style_clock": char.StyleClock,
+		}).Error
+	return format, err
+}
+
+// restoreBlock sets the block attributes of the version on a visible newline.
+func restoreBlock(ctx context.Context, tx *gorm.DB, char models.FilesContents, target common.CharacterData, site int) (common.BlockData, error) {
+	previous := toCharacterData(char)
+	block := common.BlockData{
+		Path:      target.Path,
+		Block:     target.Block,
+		Level:     target.Level,
+		Language:  target.Language,
+		Timestamp: time.Now().UnixMilli(),
+		Previous:  &previous,
+	}
+
+	_, err := applyBlock(&char, block, revertClock(char.StyleClock, block.Timestamp, site))
+	if err != nil {
+		return block, err
+	}
+
+	err = tx.WithContext(ctx).Model(&models.FilesContents{}).
+		Where("content_id = ?", char.ContentsID).
+		Updates(map[string]interface{}{
+			"block_type":     char.Block,
+			"block_level":    char.BlockLevel,
+			"block_language": char.BlockLanguage,
+			"style_clock":    char.StyleClock,
+		}).Error
+	return block, err
+}
+
+// forcedClocks stamps every attribute of the character with a clock newer than the current ones.
+func forcedClocks(styleClock string, site int) string {
+	clock := revertClock(styleClock, time.Now().UnixMilli(), site)
+
+	clocks := map[string]AttributeClock{attributeColor: clock, attributeBlock: clock}
+	for _, attribute := range styleAttributes {
+		clocks[attribute] = clock
+	}
+
+	bClocks, _ := json.Marshal(clocks)
+	return string(bClocks)
+}
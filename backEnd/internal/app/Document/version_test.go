@@ -0,0 +1,70 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	insertFile(t, fileUUID)
+	author := Author{UserID: insertUser(t, "author"), SessionID: "author-session"}
+	insertText(t, author, fileUUID, "abc")
+
+	// CASE invalid names
+	_, err := CreateVersion(t.Context(), db, author.UserID, fileUUID, "   ")
+	require.ErrorIs(t, err, ErrInvalidVersionName)
+
+	// CASE the version keeps the content at its revision
+	first, err := CreateVersion(t.Context(), db, author.UserID, fileUUID, " first ")
+	require.NoError(t, err)
+	require.Equal(t, "first", first.Name)
+	require.Equal(t, int64(3), first.Revision)
+
+	_, err = DeleteCharacter(t.Context(), db, author, fileUUID, []int{2, 0})
+	require.NoError(t, err)
+	_, err = InsertCharacter(t.Context(), db, author, fileUUID, common.CharacterData{Value: "d", Path: []int{4, 0}})
+	require.NoError(t, err)
+	_, err = CreateVersion(t.Context(), db, author.UserID, fileUUID, "second")
+	require.NoError(t, err)
+
+	versions, err := ListVersions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Empty(t, versions[0].Content)
+
+	version, err := GetVersion(t.Context(), db, first.VersionID)
+	require.NoError(t, err)
+	require.Equal(t, fileUUID, version.FileUUID)
+	require.Len(t, version.Characters, 3)
+
+	// CASE unknown and malformed IDs
+	_, err = GetVersion(t.Context(), db, "33333333-3333-3333-3333-333333333333")
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = GetVersion(t.Context(), db, "first")
+	require.ErrorIs(t, err, ErrInvalidVersionID)
+	_, err = RestoreVersion(t.Context(), db, author, "")
+	require.ErrorIs(t, err, ErrInvalidVersionID)
+
+	// CASE the restore is made of operations leading back to the version
+	operations, err := RestoreVersion(t.Context(), db, author, first.VersionID)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	require.Equal(t, OperationDelete, operations[0].OperationType)
+	require.Equal(t, OperationInsert, operations[1].OperationType)
+	require.Equal(t, "abc", visibleText(t, fileUUID))
+
+	logged, err := OperationsSince(t.Context(), db, fileUUID, 5)
+	require.NoError(t, err)
+	require.Len(t, logged, 2)
+
+	// CASE restoring the version it already matches records nothing
+	operations, err = RestoreVersion(t.Context(), db, author, first.VersionID)
+	require.NoError(t, err)
+	require.Empty(t, operations)
+}
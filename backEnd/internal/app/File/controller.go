@@ -1,21 +1,27 @@
 package file
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"os"
-	"strings"
+	"strconv"
 	"time"
 
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
 	"github.com/evanrmtl/miniDoc/internal/app/models"
 	"github.com/evanrmtl/miniDoc/internal/common"
-	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
 	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -26,7 +32,8 @@ var (
 func CreateFileController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
 	var req struct {
-		FileUUID string `json:"file_uuid" binding:"required"`
+		FileUUID    string  `json:"file_uuid" binding:"required"`
+		WorkspaceID *string `json:"workspace_id"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -35,18 +42,25 @@ func CreateFileController(c *gin.Context, db *gorm.DB) {
 	}
 
 	file_uuid := req.FileUUID
+	userID := authGuard.UserID(c)
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
-
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
-		return
-	}
-	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
-		return
+	if req.WorkspaceID != nil {
+		if uuid.Validate(*req.WorkspaceID) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id must be a UUID"})
+			return
+		}
+		count, err := gorm.G[models.WorkspaceMember](db).
+			Where("workspace_id = ?", *req.WorkspaceID).
+			Where("user_id = ?", userID).
+			Count(ctx, "user_id")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't create file"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this workspace"})
+			return
+		}
 	}
 
 	currTime := time.Now().Unix()
@@ -54,9 +68,10 @@ func CreateFileController(c *gin.Context, db *gorm.DB) {
 		FileUUID:      file_uuid,
 		FileName:      "Untitled file",
 		FileUpdatedAt: currTime,
+		WorkspaceID:   req.WorkspaceID,
 	}
 
-	err = gorm.G[models.File](db).Create(ctx, newFile)
+	err := gorm.G[models.File](db).Create(ctx, newFile)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't create file"})
 		return
@@ -68,12 +83,6 @@ func CreateFileController(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	userID, err := jwtUtils.GetUserID(token, ctx, db)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-		return
-	}
-
 	currUser, err := gorm.G[models.User](db).Where("user_id = ?", userID).First(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while finding user"})
@@ -93,6 +102,23 @@ func CreateFileController(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	if req.WorkspaceID != nil {
+		memberIDs, err := accessUtils.WorkspaceMemberIDs(*req.WorkspaceID, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+		for i, memberID := range memberIDs {
+			if memberID == userID {
+				memberIDs = append(memberIDs[:i], memberIDs[i+1:]...)
+				break
+			}
+		}
+		err = notificationUtils.NotifyFileShared(currFile.FileUUID, memberIDs, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"success": "File created"})
 
 }
@@ -114,34 +140,12 @@ func UpdateTimeFileController(c *gin.Context, db *gorm.DB) {
 
 func DeleteFileController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
-	file_uuid := c.Query("file_uuid")
+	file_uuid := authGuard.FileUUID(c)
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+	userID := authGuard.UserID(c)
 
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
-		return
-	}
-	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
-		return
-	}
-
-	userID, err := jwtUtils.GetUserID(token, ctx, db)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-		return
-	}
-
-	linkUserFile, err := gorm.G[models.UsersFile](db).Where("user_id = ?", userID).Where("file_uuid = ?", file_uuid).First(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-		return
-	}
-
-	switch linkUserFile.Role {
+	var err error
+	switch authGuard.Role(c) {
 	case models.RoleOwner:
 		_, err = gorm.G[models.File](db).Where("file_uuid = ?", file_uuid).Delete(ctx)
 		if err != nil {
@@ -162,7 +166,7 @@ func DeleteFileController(c *gin.Context, db *gorm.DB) {
 
 		c.JSON(http.StatusNoContent, nil)
 		return
-	case models.RoleCollaborator:
+	case models.RoleCollaborator, models.RoleCommenter, models.RoleViewer:
 		_, err = gorm.G[models.UsersFile](db).Where("user_id = ?", userID).Where("file_uuid = ?", file_uuid).Delete(ctx)
 		if err != nil {
 			log.Println(err)
@@ -177,52 +181,61 @@ func DeleteFileController(c *gin.Context, db *gorm.DB) {
 
 func ShareFileController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
 
+	// usernames are shared as Collaborator, users carry their own role
+	// expires_at is the unix time the accesses are revoked, 0 to keep them
 	var req struct {
-		FileUUID  string   `json:"file_uuid" binding:"required"`
-		Usernames []string `json:"usernames" binding:"required"`
+		Usernames []string             `json:"usernames"`
+		Users     []common.SharedUsers `json:"users"`
+		ExpiresAt int64                `json:"expires_at"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
-
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
-		return
-	}
-
+	roles, err := shareRoles(req.Usernames, req.Users)
 	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(req.Usernames) == 0 {
+	if len(roles) == 0 {
 		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Must have at least 1 user to shares"})
 		return
 	}
 
-	var userIDs []uint32
-	err = db.Model(&models.User{}).Where("username IN ?", req.Usernames).Pluck("user_id", &userIDs).Error
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	if req.ExpiresAt != 0 && req.ExpiresAt <= time.Now().Unix() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
 		return
 	}
 
-	fileRequire, err := gorm.G[models.File](db).Where("file_uuid = ?", req.FileUUID).First(ctx)
+	usernames := make([]string, 0, len(roles))
+	for username := range roles {
+		usernames = append(usernames, username)
+	}
+
+	sharedWith, err := gorm.G[models.User](db).Select("user_id", "username").Where("username IN ?", usernames).Find(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		return
 	}
 
+	var userIDs []uint32
+	for _, user := range sharedWith {
+		userIDs = append(userIDs, user.UserID)
+	}
+
 	var errCreate error
-	for _, userID := range userIDs {
-		err := gorm.G[models.UsersFile](db).Create(ctx, &models.UsersFile{UserID: userID, FileUUID: req.FileUUID})
+	for _, user := range sharedWith {
+		err := gorm.G[models.UsersFile](db).Create(ctx, &models.UsersFile{
+			UserID:    user.UserID,
+			FileUUID:  fileUUID,
+			Role:      roles[user.Username],
+			ExpiresAt: req.ExpiresAt,
+		})
 		if err != nil {
 			errCreate = err
 			break
@@ -233,36 +246,8 @@ func ShareFileController(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	var users []common.SharedUsers
-
-	err = db.Table("users").
-		Select("users.username, users_files.role").
-		Joins("JOIN users_files ON users.user_id = users_files.user_id").
-		Where("users_files.file_uuid = ?", fileRequire.FileUUID).
-		Scan(&users).Error
-
-	var errPublish error
-	for _, userID := range userIDs {
-		currTime := time.Now().Unix()
-
-		newNotification := common.UserNotification{
-			NotificationType: "file_shared",
-			TargetUser:       userID,
-			FileData: common.ShareFileData{
-				FileUUID:      fileRequire.FileUUID,
-				FileName:      fileRequire.FileName,
-				FileUpdatedAt: currTime,
-				SharedUser:    users,
-			},
-		}
-
-		err = redisUtils.PublishUserSharedNotification(ctx, newNotification)
-		if err != nil {
-			errPublish = err
-			break
-		}
-	}
-	if errPublish != nil {
+	err = notificationUtils.NotifyFileShared(fileUUID, userIDs, ctx, db)
+	if err != nil {
 		c.JSON(http.StatusPartialContent, gin.H{"error": "Some user(s) couldn't be added"})
 		return
 	}
@@ -272,72 +257,110 @@ func ShareFileController(c *gin.Context, db *gorm.DB) {
 func GetFileController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+	userID := authGuard.UserID(c)
 
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+	fileUUIDs, err := accessUtils.FileUUIDs(userID, ctx, db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding usersfiles"})
 		return
 	}
 
-	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
-		return
+	query := gorm.G[models.File](db).Select("file_uuid", "file_name", "file_updated_at", "workspace_id").Where("file_uuid IN ?", fileUUIDs)
+	if workspaceID := c.Query("workspace_id"); workspaceID != "" {
+		if uuid.Validate(workspaceID) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id must be a UUID"})
+			return
+		}
+		query = query.Where("workspace_id = ?", workspaceID)
 	}
-	userID, err := jwtUtils.GetUserID(token, ctx, db)
+
+	files, err := query.Order("file_updated_at desc").Find(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding files"})
 		return
 	}
+	c.JSON(http.StatusOK, files)
+}
 
-	userfiles, err := gorm.G[models.UsersFile](db).Where("user_id = ?", userID).Find(ctx)
+func GetFileContentController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	content, err := document.LoadDocument(ctx, db, fileUUID)
+	if errors.Is(err, document.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding usersfiles"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading file content"})
 		return
 	}
+	c.JSON(http.StatusOK, content)
+}
 
-	fileUUIDs := make([]string, len(userfiles))
+// ExportFileController streams the content of the file as an attachment in the requested format.
+func ExportFileController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
 
-	for i, userfile := range userfiles {
-		fileUUIDs[i] = userfile.FileUUID
+	format := c.DefaultQuery("format", document.ExportMarkdown)
+	contentType, err := document.ExportContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	files, err := gorm.G[models.File](db).Select("file_uuid", "file_name", "file_updated_at").Where("file_uuid IN ?", fileUUIDs).Order("file_updated_at desc").Find(ctx)
+	file, err := gorm.G[models.File](db).Where("file_uuid = ?", fileUUID).First(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding files"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
-	c.JSON(http.StatusOK, files)
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName + "." + format}))
+	c.Status(http.StatusOK)
+	err = document.WriteExport(c.Writer, format, file.FileName, document.DocumentCharacters(ctx, db, fileUUID))
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading file content"})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+	}
 }
 
-func GetSharedUserController(c *gin.Context, db *gorm.DB) {
+func GetFileOperationsController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
-	fileUUID := c.Query("file_uuid")
-
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+	fileUUID := authGuard.FileUUID(c)
 
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a revision number"})
 		return
 	}
 
-	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
+	operations, err := document.OperationsSince(ctx, db, fileUUID, since)
+	if errors.Is(err, document.ErrRevisionCompacted) {
+		c.JSON(http.StatusGone, gin.H{"error": "Revision too old, the file content must be reloaded"})
 		return
 	}
-
-	currUserID, err := jwtUtils.GetUserID(token, ctx, db)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading file operations"})
 		return
 	}
+	c.JSON(http.StatusOK, operations)
+}
+
+func GetSharedUserController(c *gin.Context, db *gorm.DB) {
+	fileUUID := authGuard.FileUUID(c)
+
+	currUserID := authGuard.UserID(c)
 
 	var users []common.SharedUsers
 
-	err = db.Table("users").
+	err := db.Table("users").
 		Select("users.username, users_files.role").
 		Joins("JOIN users_files ON users.user_id = users_files.user_id").
 		Where("users_files.file_uuid = ?", fileUUID).
@@ -353,28 +376,253 @@ func GetSharedUserController(c *gin.Context, db *gorm.DB) {
 
 func RemovedUserController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
-	fileUUID := c.Query("file_uuid")
+	fileUUID := authGuard.FileUUID(c)
 	username := c.Query("username")
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+	fmt.Println("fileUUID: ", fileUUID, "   username: ", username)
+	rowsAffected, err := gorm.G[models.UsersFile](db).Where(`user_id = (
+		SELECT user_id FROM users WHERE username = ?
+		) AND file_uuid = ?`, username, fileUUID).
+		Delete(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this user"})
+		return
+	}
+
+	user, err := gorm.G[models.User](db).Where("username = ?", username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this user"})
+		return
+	}
 
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+	// a group may still give access to the file
+	err = notificationUtils.NotifyAccessChanged(fileUUID, []uint32{user.UserID}, ctx, db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't revoke user from file"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"success": "Access removed successfully"})
+}
+
+func DisconnectFromFile(c *gin.Context, db *gorm.DB) {
+}
+
+func CreateVersionController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userID := authGuard.UserID(c)
+
+	version, err := document.CreateVersion(ctx, db, userID, fileUUID, req.Name)
+	if errors.Is(err, document.ErrInvalidVersionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, document.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
 	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while creating version"})
 		return
 	}
+	c.JSON(http.StatusCreated, version)
+}
 
-	fmt.Println("fileUUID: ", fileUUID, "   username: ", username)
-	rowsAffected, err := gorm.G[models.UsersFile](db).Where(`user_id = (
-		SELECT user_id FROM users WHERE username = ?
-		) AND file_uuid = ?`, username, fileUUID).
-		Delete(ctx)
+func GetVersionsController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	versions, err := document.ListVersions(ctx, db, fileUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func GetVersionController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	versionID := c.Query("version_id")
+
+	userID := authGuard.UserID(c)
+
+	version, err := document.GetVersion(ctx, db, versionID)
+	if errors.Is(err, document.ErrInvalidVersionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, document.ErrVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading version"})
+		return
+	}
+
+	owner, err := isOwner(ctx, db, userID, version.FileUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if !owner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage versions"})
+		return
+	}
+	c.JSON(http.StatusOK, version)
+}
+
+func RestoreVersionController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		VersionID string `json:"version_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userID := authGuard.UserID(c)
+
+	version, err := document.GetVersion(ctx, db, req.VersionID)
+	if errors.Is(err, document.ErrInvalidVersionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, document.ErrVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading version"})
+		return
+	}
+
+	owner, err := isOwner(ctx, db, userID, version.FileUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if !owner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage versions"})
+		return
+	}
+
+	author := document.Author{UserID: userID}
+	operations, err := document.RestoreVersion(ctx, db, author, req.VersionID)
+	if errors.Is(err, document.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while restoring version"})
+		return
+	}
+
+	// the restore has no session, every collaborator of the file receives the operations
+	for _, operation := range operations {
+		err = redisUtils.BroadcastDocumentOperation(ctx, operation)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"operations": operations})
+}
+
+func GetFileDiffController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	// from and to are either revisions or version IDs
+	var err error
+	var contents [2]document.Content
+	for i, reference := range []string{c.Query("from"), c.Query("to")} {
+		contents[i], err = document.ResolveContent(ctx, db, fileUUID, reference)
+		switch {
+		case errors.Is(err, document.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		case errors.Is(err, document.ErrVersionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+			return
+		case errors.Is(err, document.ErrInvalidRevision), errors.Is(err, document.ErrInvalidReference):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, document.ErrRevisionCompacted):
+			c.JSON(http.StatusGone, gin.H{"error": "Revision is too old, compare saved versions instead"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error while loading file content"})
+			return
+		}
+	}
+
+	diff, err := document.CompareContents(ctx, db, contents[0], contents[1], c.Query("granularity"))
+	if errors.Is(err, document.ErrInvalidGranularity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while comparing versions"})
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+func ChangeRoleController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Role     string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !accessUtils.IsShareRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidShareRole.Error()})
+		return
+	}
+
+	if authGuard.Role(c) != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can change roles"})
+		return
+	}
+
+	user, err := gorm.G[models.User](db).Where("username = ?", req.Username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this user"})
+		return
+	}
+
+	// the owner keeps their role, ownership is never given through a role change
+	rowsAffected, err := gorm.G[models.UsersFile](db).
+		Where("user_id = ?", user.UserID).
+		Where("file_uuid = ?", fileUUID).
+		Where("role != ?", models.RoleOwner).
+		Update(ctx, "role", req.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		return
@@ -384,60 +632,473 @@ func RemovedUserController(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	user, err := gorm.G[models.User](db).Where("username = ?", username).First(ctx)
+	// a group may give a higher role than the new one
+	err = notificationUtils.NotifyAccessChanged(fileUUID, []uint32{user.UserID}, ctx, db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't notify user of the new role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Role changed successfully"})
+}
+
+func TransferOwnershipController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	userID := authGuard.UserID(c)
+
+	owner, err := gorm.G[models.User](db).Where("user_id = ?", userID).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while finding user"})
+		return
+	}
+
+	newOwner, err := gorm.G[models.User](db).Where("username = ?", req.Username).First(ctx)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this user"})
 		return
 	}
 
-	newNotification := common.UserNotification{
-		NotificationType: "file_revoke",
-		TargetUser:       user.UserID,
-		FileData: common.RevokeFileData{
-			FileUUID: fileUUID,
-		},
+	err = transferOwnership(ctx, db, fileUUID, owner.UserID, newOwner.UserID)
+	if errors.Is(err, ErrNotMember) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this user"})
+		return
+	}
+	if errors.Is(err, ErrNotOwner) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can transfer the file"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't transfer ownership"})
+		return
+	}
+
+	roles := map[uint32]string{
+		owner.UserID:    models.RoleCollaborator,
+		newOwner.UserID: models.RoleOwner,
+	}
+	for targetUser, role := range roles {
+		newNotification := common.UserNotification{
+			NotificationType: "ownership_transferred",
+			TargetUser:       targetUser,
+			FileData: common.OwnershipFileData{
+				FileUUID:      fileUUID,
+				Role:          role,
+				PreviousOwner: owner.Username,
+				NewOwner:      newOwner.Username,
+			},
+		}
+
+		err = notificationUtils.Notify(newNotification, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
 	}
 
-	err = redisUtils.PublishUserRevokeNotification(ctx, newNotification)
+	c.JSON(http.StatusOK, gin.H{"success": "Ownership transferred successfully"})
+}
+
+func GetSuggestionsController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	suggestions, err := document.ListSuggestions(ctx, db, fileUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't revoke user from file"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding suggestions"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
 
-	c.JSON(http.StatusOK, gin.H{"success": "Access removed successfully"})
+// AcceptSuggestionController applies the suggestion as an operation of the user, who must
+// be allowed to edit the file.
+func AcceptSuggestionController(c *gin.Context, db *gorm.DB) {
+	resolveSuggestion(c, db, true)
 }
 
-func TokenExpiredValidSession(token string, c *gin.Context, ctx context.Context, db *gorm.DB) {
-	username, err := jwtUtils.GetUsername(token, ctx, db)
+// RejectSuggestionController discards the suggestion, editors reject any suggestion
+// and the other users their own ones.
+func RejectSuggestionController(c *gin.Context, db *gorm.DB) {
+	resolveSuggestion(c, db, false)
+}
+
+func resolveSuggestion(c *gin.Context, db *gorm.DB, accept bool) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		SuggestionID string `json:"suggestion_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	author := document.Author{UserID: authGuard.UserID(c)}
+	var suggestion document.Suggestion
+	var operation *common.DocumentOperation
+	var err error
+	event := document.SuggestionRejected
+	if accept {
+		event = document.SuggestionAccepted
+		suggestion, operation, err = document.AcceptSuggestion(ctx, db, author, authGuard.Role(c), fileUUID, req.SuggestionID)
+	} else {
+		suggestion, err = document.RejectSuggestion(ctx, db, author, authGuard.Role(c), fileUUID, req.SuggestionID)
+	}
+	switch {
+	case errors.Is(err, document.ErrSuggestionRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, document.ErrSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suggestion not found"})
+		return
+	case err != nil:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while resolving suggestion"})
+		return
+	}
+
+	// the resolution has no session, every collaborator of the file receives it
+	broadcast := []common.DocumentOperation{{
+		OperationType: event,
+		FileUUID:      fileUUID,
+		UserID:        author.UserID,
+		Data:          suggestion,
+	}}
+	if operation != nil {
+		broadcast = append(broadcast, *operation)
+	}
+	for _, op := range broadcast {
+		err = redisUtils.BroadcastDocumentOperation(ctx, op)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestion": suggestion, "operation": operation})
+}
+
+func CreateInviteController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	// expires_in is in seconds, 0 for a link that never expires and max_uses 0 for unlimited uses
+	var req struct {
+		Role      string `json:"role"`
+		ExpiresIn int64  `json:"expires_in"`
+		MaxUses   int    `json:"max_uses"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+
+	invite, err := createInvite(ctx, db, fileUUID, authGuard.UserID(c), req.Role, req.ExpiresIn, req.MaxUses)
+	if errors.Is(err, ErrInvalidShareRole) || errors.Is(err, ErrInvalidInvite) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create invite link"})
 		return
 	}
-	newJWT, err := jwtUtils.CreateJWT(ctx, username, db)
+	c.JSON(http.StatusCreated, invite)
+}
+
+func GetInvitesController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	invites, err := gorm.G[models.FileInvite](db).Where("file_uuid = ?", fileUUID).Order("created_at desc").Find(ctx)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		return
 	}
-	c.JSON(http.StatusConflict, gin.H{
-		"error":  jwtUtils.ErrTokenExpired,
-		"newJWT": newJWT,
-	})
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
 }
 
-func DisconnectFromFile(c *gin.Context, db *gorm.DB) {
+func RevokeInviteController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+	inviteID := c.Query("invite_id")
+
+	rowsAffected, err := gorm.G[models.FileInvite](db).Where("invite_id = ?", inviteID).Where("file_uuid = ?", fileUUID).Delete(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite link not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": "Invite link revoked"})
+}
+
+func RedeemInviteController(c *gin.Context, db *gorm.DB) {
 	ctx := c.Request.Context()
 
-	bearer := c.Request.Header.Get("Authorization")
-	token := strings.TrimPrefix(bearer, "Bearer ")
-	err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
 
-	if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+
+	userID := authGuard.UserID(c)
+
+	fileUUID, created, err := redeemInvite(ctx, db, req.Token, userID)
+	if errors.Is(err, ErrInviteNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite link not found"})
+		return
+	}
+	if errors.Is(err, ErrInviteExpired) {
+		c.JSON(http.StatusGone, gin.H{"error": "Invite link expired"})
+		return
+	}
+	if errors.Is(err, ErrInviteExhausted) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invite link reached its maximum number of uses"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't redeem invite link"})
+		return
+	}
+
+	if created {
+		err = notificationUtils.NotifyFileShared(fileUUID, []uint32{userID}, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"file_uuid": fileUUID})
+}
+
+// RequestAccessController asks the owner of a file for an access, the user has no role on it yet.
+func RequestAccessController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		FileUUID string `json:"file_uuid" binding:"required"`
+		Message  string `json:"message"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	request, err := requestAccess(ctx, db, req.FileUUID, authGuard.UserID(c), req.Message)
+	switch {
+	case errors.Is(err, ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrFindFile):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	case errors.Is(err, ErrAlreadyMember), errors.Is(err, ErrRequestPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't request access"})
+		return
+	}
+
+	err = notificationUtils.NotifyAccessRequested(request, ctx, db)
+	if err != nil {
+		log.Println(err)
+	}
+	c.JSON(http.StatusCreated, request)
+}
+
+type AccessRequestData struct {
+	RequestID string `json:"request_id"`
+	Username  string `json:"username"`
+	Message   string `json:"message"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func GetAccessRequestsController(c *gin.Context, db *gorm.DB) {
+	fileUUID := authGuard.FileUUID(c)
+
+	var requests []AccessRequestData
+	err := db.Table("access_requests").
+		Select("access_requests.request_id, users.username, access_requests.message, access_requests.created_at").
+		Joins("JOIN users ON users.user_id = access_requests.user_id").
+		Where("access_requests.file_uuid = ?", fileUUID).
+		Where("access_requests.status = ?", models.AccessRequestPending).
+		Order("access_requests.created_at").
+		Scan(&requests).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// ResolveAccessRequestController approves the request with a role, Viewer by default, or denies it.
+func ResolveAccessRequestController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		RequestID string `json:"request_id" binding:"required"`
+		Approve   bool   `json:"approve"`
+		Role      string `json:"role"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	role := ""
+	if req.Approve {
+		role = req.Role
+		if role == "" {
+			role = models.RoleViewer
+		}
+	}
+
+	request, err := resolveAccessRequest(ctx, db, fileUUID, req.RequestID, role)
+	switch {
+	case errors.Is(err, ErrInvalidShareRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access request not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't resolve access request"})
+		return
+	}
+
+	err = notificationUtils.NotifyAccessResolved(request, ctx, db)
+	if err != nil {
+		log.Println(err)
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+func ShareGroupController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+
+	var req struct {
+		GroupID string `json:"group_id" binding:"required"`
+		Role    string `json:"role"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleCollaborator
+	}
+	if !accessUtils.IsShareRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidShareRole.Error()})
+		return
+	}
+	if uuid.Validate(req.GroupID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_id must be a UUID"})
+		return
+	}
+
+	nbGroup, err := gorm.G[models.Group](db).Where("group_id = ?", req.GroupID).Count(ctx, "group_id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if nbGroup == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	// sharing again with a group changes its role
+	err = gorm.G[models.GroupFile](db, clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "file_uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(ctx, &models.GroupFile{GroupID: req.GroupID, FileUUID: fileUUID, Role: req.Role})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't share file with group"})
+		return
+	}
+
+	memberIDs, err := accessUtils.GroupMemberIDs(req.GroupID, ctx, db)
+	if err == nil {
+		err = notificationUtils.NotifyFileShared(fileUUID, memberIDs, ctx, db)
+	}
+	if err != nil {
+		c.JSON(http.StatusPartialContent, gin.H{"error": "Some member(s) couldn't be notified"})
+		return
+	}
+	c.JSON(http.StatusOK, "File shared with group")
+}
+
+func GetSharedGroupsController(c *gin.Context, db *gorm.DB) {
+	fileUUID := authGuard.FileUUID(c)
+
+	var groups []struct {
+		GroupID string `json:"group_id"`
+		Name    string `json:"name"`
+		Role    string `json:"role"`
+	}
+
+	err := db.Table("groups").
+		Select("groups.group_id, groups.name, group_files.role").
+		Joins("JOIN group_files ON groups.group_id = group_files.group_id").
+		Where("group_files.file_uuid = ?", fileUUID).
+		Scan(&groups).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func RemovedGroupController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	fileUUID := authGuard.FileUUID(c)
+	groupID := c.Query("group_id")
+	if uuid.Validate(groupID) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_id must be a UUID"})
+		return
+	}
+
+	rowsAffected, err := gorm.G[models.GroupFile](db).Where("group_id = ?", groupID).Where("file_uuid = ?", fileUUID).Delete(ctx)
 	if err != nil {
-		TokenExpiredValidSession(token, c, ctx, db)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No access found for this group"})
 		return
 	}
 
+	memberIDs, err := accessUtils.GroupMemberIDs(groupID, ctx, db)
+	if err == nil {
+		err = notificationUtils.NotifyAccessChanged(fileUUID, memberIDs, ctx, db)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't revoke group from file"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Access removed successfully"})
 }
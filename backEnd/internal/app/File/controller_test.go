@@ -0,0 +1,266 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	setupTestRS256KeyPair()
+
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+	redisUtils.CreateRedis(context.Background())
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+// createVersionRoutes serves the version endpoints like the file routes do, the owner
+// being checked by the controllers.
+func createVersionRoutes() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authGuard.Authenticate(testenv.DB))
+	r.GET("/version/get", func(c *gin.Context) {
+		GetVersionController(c, testenv.DB)
+	})
+	r.POST("/version/restore", func(c *gin.Context) {
+		RestoreVersionController(c, testenv.DB)
+	})
+	return r
+}
+
+// createContentRoutes serves the content endpoint like the file routes do.
+func createContentRoutes() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authGuard.Authenticate(testenv.DB))
+	r.GET("/content", authGuard.RequireFileRole(testenv.DB), func(c *gin.Context) {
+		GetFileContentController(c, testenv.DB)
+	})
+	return r
+}
+
+// createTransferRoutes serves the ownership transfer like the file routes do.
+func createTransferRoutes() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(authGuard.Authenticate(testenv.DB))
+	r.POST("/transferOwnership", authGuard.RequireFileRole(testenv.DB, models.RoleOwner), func(c *gin.Context) {
+		TransferOwnershipController(c, testenv.DB)
+	})
+	return r
+}
+
+func request(router *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(writer, req)
+	return writer
+}
+
+func TestVersionControllers(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	router := createVersionRoutes()
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	insertUser(t, "other")
+	ownerToken, err := jwtUtils.CreateJWT(t.Context(), "owner", db)
+	require.NoError(t, err)
+	otherToken, err := jwtUtils.CreateJWT(t.Context(), "other", db)
+	require.NoError(t, err)
+
+	version, err := document.CreateVersion(t.Context(), db, ownerID, fileUUID, "first")
+	require.NoError(t, err)
+
+	// CASE the owner gets the version
+	writer := request(router, http.MethodGet, "/version/get?version_id="+version.VersionID, ownerToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+
+	// CASE another user
+	writer = request(router, http.MethodGet, "/version/get?version_id="+version.VersionID, otherToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+
+	// CASE malformed and unknown IDs
+	for _, versionID := range []string{"", "first", "1111"} {
+		writer = request(router, http.MethodGet, "/version/get?version_id="+versionID, ownerToken, "")
+		require.Equal(t, http.StatusBadRequest, writer.Code, versionID)
+
+		writer = request(router, http.MethodPost, "/version/restore", ownerToken, fmt.Sprintf(`{"version_id": "%s"}`, versionID))
+		require.Equal(t, http.StatusBadRequest, writer.Code, versionID)
+	}
+
+	unknown := "33333333-3333-3333-3333-333333333333"
+	writer = request(router, http.MethodGet, "/version/get?version_id="+unknown, ownerToken, "")
+	require.Equal(t, http.StatusNotFound, writer.Code)
+	writer = request(router, http.MethodPost, "/version/restore", ownerToken, fmt.Sprintf(`{"version_id": "%s"}`, unknown))
+	require.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func TestGetFileContentController(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	router := createContentRoutes()
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	viewerID := insertUser(t, "viewer")
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: viewerID, FileUUID: fileUUID, Role: models.RoleViewer}))
+	insertUser(t, "other")
+	viewerToken, err := jwtUtils.CreateJWT(t.Context(), "viewer", db)
+	require.NoError(t, err)
+	otherToken, err := jwtUtils.CreateJWT(t.Context(), "other", db)
+	require.NoError(t, err)
+
+	// CASE empty file
+	writer := request(router, http.MethodGet, "/content?file_uuid="+fileUUID, viewerToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var content document.Content
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &content))
+	require.Equal(t, int64(0), content.Revision)
+	require.Empty(t, content.Characters)
+
+	// characters inserted out of document order, a nested path between two others
+	author := document.Author{UserID: ownerID, SessionID: "content-session"}
+	for _, char := range []common.CharacterData{
+		{Value: "c", Path: []int{2, 0}},
+		{Value: "a", Path: []int{1, 0}, Style: models.StyleBold},
+		{Value: "x", Path: []int{1, 0, 3, 0}},
+		{Value: "b", Path: []int{1, 0, 5, 0}, Color: "#00ff00"},
+	} {
+		_, err := document.InsertCharacter(t.Context(), db, author, fileUUID, char)
+		require.NoError(t, err)
+	}
+	_, err = document.DeleteCharacter(t.Context(), db, author, fileUUID, []int{1, 0, 3, 0})
+	require.NoError(t, err)
+
+	// CASE the visible characters ordered by decoded path, at the current revision
+	writer = request(router, http.MethodGet, "/content?file_uuid="+fileUUID, viewerToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &content))
+	require.Equal(t, fileUUID, content.FileUUID)
+	require.Equal(t, int64(5), content.Revision)
+	require.Equal(t, []common.CharacterData{
+		{Value: "a", Path: []int{1, 0}, Style: models.StyleBold},
+		{Value: "b", Path: []int{1, 0, 5, 0}, Color: "#00ff00"},
+		{Value: "c", Path: []int{2, 0}},
+	}, content.Characters)
+
+	// CASE a user without access
+	writer = request(router, http.MethodGet, "/content?file_uuid="+fileUUID, otherToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+
+	// CASE unknown file
+	writer = request(router, http.MethodGet, "/content?file_uuid=33333333-3333-3333-3333-333333333333", viewerToken, "")
+	require.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func TestTransferOwnershipController(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	router := createTransferRoutes()
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	collaboratorID := insertUser(t, "collaborator")
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: collaboratorID, FileUUID: fileUUID, Role: models.RoleCollaborator}))
+	insertUser(t, "outsider")
+	ownerToken, err := jwtUtils.CreateJWT(t.Context(), "owner", db)
+	require.NoError(t, err)
+	collaboratorToken, err := jwtUtils.CreateJWT(t.Context(), "collaborator", db)
+	require.NoError(t, err)
+
+	transfer := func(token string, username string) int {
+		body := fmt.Sprintf(`{"file_uuid": "%s", "username": "%s"}`, fileUUID, username)
+		return request(router, http.MethodPost, "/transferOwnership", token, body).Code
+	}
+
+	// CASE only the owner transfers
+	require.Equal(t, http.StatusForbidden, transfer(collaboratorToken, "collaborator"))
+
+	// CASE unknown user and user without access
+	require.Equal(t, http.StatusNotFound, transfer(ownerToken, "unknown"))
+	require.Equal(t, http.StatusNotFound, transfer(ownerToken, "outsider"))
+
+	// CASE both users are notified of the transfer with their new role
+	require.Equal(t, http.StatusOK, transfer(ownerToken, "collaborator"))
+
+	notifications, err := gorm.G[models.Notification](db).Where("notification_type = ?", "ownership_transferred").Find(t.Context())
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	roles := map[uint32]string{}
+	for _, notification := range notifications {
+		var data common.OwnershipFileData
+		require.NoError(t, json.Unmarshal([]byte(notification.Payload), &data))
+		require.Equal(t, fileUUID, data.FileUUID)
+		require.Equal(t, "owner", data.PreviousOwner)
+		require.Equal(t, "collaborator", data.NewOwner)
+		roles[notification.UserID] = data.Role
+	}
+	require.Equal(t, map[uint32]string{ownerID: models.RoleCollaborator, collaboratorID: models.RoleOwner}, roles)
+
+	// CASE the previous owner lost the right to transfer
+	require.Equal(t, http.StatusForbidden, transfer(ownerToken, "collaborator"))
+}
+
+func setupTestRS256KeyPair() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  privateRSA key: %v", err))
+	}
+
+	privateKeyPEM := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, privateKeyPEM)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  publicRSA key: %v", err))
+	}
+	publicKeyPEM := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	}
+	var publicKeyBuf bytes.Buffer
+	pem.Encode(&publicKeyBuf, publicKeyPEM)
+
+	os.Setenv("RS256_PRIVATE_KEY", privateKeyBuf.String())
+	os.Setenv("RS256_PUBLIC_KEY", publicKeyBuf.String())
+}
@@ -1 +1,350 @@
 package file
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidShareRole = errors.New("role must be Collaborator, Commenter or Viewer")
+	ErrNotMember        = errors.New("user is not a member of this file")
+	ErrNotOwner         = errors.New("user is not the owner of this file")
+	ErrInvalidInvite    = errors.New("expiry and max uses can't be negative")
+	ErrInviteNotFound   = errors.New("invite link not found")
+	ErrInviteExpired    = errors.New("invite link expired")
+	ErrInviteExhausted  = errors.New("invite link reached its maximum number of uses")
+	ErrAlreadyMember    = errors.New("user already has access to this file")
+	ErrRequestPending   = errors.New("an access request is already pending for this file")
+	ErrRequestNotFound  = errors.New("access request not found")
+	ErrInvalidMessage   = errors.New("message must be at most 500 characters")
+)
+
+// isOwner reports whether the user owns the file, as checked by DeleteFileController.
+func isOwner(ctx context.Context, db *gorm.DB, userID uint32, fileUUID string) (bool, error) {
+	role, err := accessUtils.GetRole(userID, fileUUID, ctx, db)
+	if errors.Is(err, accessUtils.ErrNoAccess) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role == models.RoleOwner, nil
+}
+
+// shareRoles merges the plain usernames of a share request, given the default role,
+// with the users given with an explicit role.
+func shareRoles(usernames []string, users []common.SharedUsers) (map[string]string, error) {
+	roles := make(map[string]string, len(usernames)+len(users))
+	for _, username := range usernames {
+		roles[username] = models.RoleCollaborator
+	}
+	for _, user := range users {
+		if user.Role == "" {
+			user.Role = models.RoleCollaborator
+		}
+		if !accessUtils.IsShareRole(user.Role) {
+			return nil, ErrInvalidShareRole
+		}
+		roles[user.Username] = user.Role
+	}
+	return roles, nil
+}
+
+// transferOwnership makes the member the owner of the file and demotes the current owner
+// to Collaborator. The owner row is locked so concurrent transfers can't leave two owners.
+func transferOwnership(ctx context.Context, db *gorm.DB, fileUUID string, ownerID uint32, newOwnerID uint32) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := gorm.G[models.UsersFile](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", ownerID).
+			Where("file_uuid = ?", fileUUID).
+			Where("role = ?", models.RoleOwner).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotOwner
+		}
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := gorm.G[models.UsersFile](tx).
+			Where("user_id = ?", newOwnerID).
+			Where("file_uuid = ?", fileUUID).
+			Where("role != ?", models.RoleOwner).
+			Where("expires_at = 0 OR expires_at > ?", time.Now().Unix()).
+			Updates(ctx, models.UsersFile{Role: models.RoleOwner})
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotMember
+		}
+
+		// an owner never loses the file
+		_, err = gorm.G[models.UsersFile](tx).
+			Where("user_id = ?", newOwnerID).
+			Where("file_uuid = ?", fileUUID).
+			Update(ctx, "expires_at", 0)
+		if err != nil {
+			return err
+		}
+
+		_, err = gorm.G[models.UsersFile](tx).
+			Where("user_id = ?", ownerID).
+			Where("file_uuid = ?", fileUUID).
+			Update(ctx, "role", models.RoleCollaborator)
+		return err
+	})
+}
+
+// createInvite stores a new invite link on the file. A zero expiresIn or maxUses means no limit.
+func createInvite(ctx context.Context, db *gorm.DB, fileUUID string, userID uint32, role string, expiresIn int64, maxUses int) (models.FileInvite, error) {
+	var invite models.FileInvite
+
+	if !accessUtils.IsShareRole(role) {
+		return invite, ErrInvalidShareRole
+	}
+	if expiresIn < 0 || maxUses < 0 {
+		return invite, ErrInvalidInvite
+	}
+
+	bToken := make([]byte, 32)
+	_, err := rand.Read(bToken)
+	if err != nil {
+		return invite, err
+	}
+
+	currTime := time.Now().Unix()
+	invite = models.FileInvite{
+		FileUUID:  fileUUID,
+		Token:     base64.RawURLEncoding.EncodeToString(bToken),
+		Role:      role,
+		CreatedBy: userID,
+		CreatedAt: currTime,
+		MaxUses:   maxUses,
+	}
+	if expiresIn > 0 {
+		invite.ExpiresAt = currTime + expiresIn
+	}
+
+	err = gorm.G[models.FileInvite](db).Create(ctx, &invite)
+	return invite, err
+}
+
+// redeemInvite gives the user access to the file of the invite with its role. A user who
+// already has access keeps their role and doesn't consume a use of the link, an expired
+// access is replaced.
+// Return the file UUID and whether an access was created.
+func redeemInvite(ctx context.Context, db *gorm.DB, token string, userID uint32) (string, bool, error) {
+	var fileUUID string
+	created := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// the lock orders the redeems of the same link so max uses can't be exceeded
+		invite, err := gorm.G[models.FileInvite](tx, clause.Locking{Strength: "UPDATE"}).Where("token = ?", token).First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		fileUUID = invite.FileUUID
+
+		if invite.ExpiresAt != 0 && invite.ExpiresAt <= time.Now().Unix() {
+			return ErrInviteExpired
+		}
+
+		nbAccess, err := gorm.G[models.UsersFile](tx).
+			Where("user_id = ?", userID).
+			Where("file_uuid = ?", invite.FileUUID).
+			Where("expires_at = 0 OR expires_at > ?", time.Now().Unix()).
+			Count(ctx, "user_id")
+		if err != nil {
+			return err
+		}
+		if nbAccess > 0 {
+			return nil
+		}
+
+		if invite.MaxUses != 0 && invite.Uses >= invite.MaxUses {
+			return ErrInviteExhausted
+		}
+
+		// an expired access not deleted yet is overwritten
+		err = gorm.G[models.UsersFile](tx, clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "file_uuid"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"role": invite.Role, "expires_at": 0}),
+		}).Create(ctx, &models.UsersFile{UserID: userID, FileUUID: invite.FileUUID, Role: invite.Role})
+		if err != nil {
+			return err
+		}
+
+		_, err = gorm.G[models.FileInvite](tx).Where("invite_id = ?", invite.InviteID).Update(ctx, "uses", gorm.Expr("uses + 1"))
+		created = err == nil
+		return err
+	})
+	return fileUUID, created, err
+}
+
+// requestAccess records a pending access request of the user on the file,
+// a user already having access to the file has nothing to request.
+func requestAccess(ctx context.Context, db *gorm.DB, fileUUID string, userID uint32, message string) (models.AccessRequest, error) {
+	var request models.AccessRequest
+
+	message = strings.TrimSpace(message)
+	if len(message) > 500 {
+		return request, ErrInvalidMessage
+	}
+
+	_, err := gorm.G[models.File](db).Where("file_uuid = ?", fileUUID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return request, ErrFindFile
+	}
+	if err != nil {
+		return request, err
+	}
+
+	_, err = accessUtils.GetRole(userID, fileUUID, ctx, db)
+	if err == nil {
+		return request, ErrAlreadyMember
+	}
+	if !errors.Is(err, accessUtils.ErrNoAccess) {
+		return request, err
+	}
+
+	nbPending, err := gorm.G[models.AccessRequest](db).
+		Where("file_uuid = ?", fileUUID).
+		Where("user_id = ?", userID).
+		Where("status = ?", models.AccessRequestPending).
+		Count(ctx, "request_id")
+	if err != nil {
+		return request, err
+	}
+	if nbPending > 0 {
+		return request, ErrRequestPending
+	}
+
+	request = models.AccessRequest{
+		FileUUID:  fileUUID,
+		UserID:    userID,
+		Message:   message,
+		Status:    models.AccessRequestPending,
+		CreatedAt: time.Now().Unix(),
+	}
+	err = gorm.G[models.AccessRequest](db).Create(ctx, &request)
+	return request, err
+}
+
+// resolveAccessRequest approves the pending request with the role, or denies it when role is empty.
+// The approved role replaces an access the requester got in the meantime, an expiring or expired
+// one included, unless the requester became the owner.
+func resolveAccessRequest(ctx context.Context, db *gorm.DB, fileUUID string, requestID string, role string) (models.AccessRequest, error) {
+	var request models.AccessRequest
+	if role != "" && !accessUtils.IsShareRole(role) {
+		return request, ErrInvalidShareRole
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		request, err = gorm.G[models.AccessRequest](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("request_id = ?", requestID).
+			Where("file_uuid = ?", fileUUID).
+			Where("status = ?", models.AccessRequestPending).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		request.Status = models.AccessRequestDenied
+		if role != "" {
+			request.Status = models.AccessRequestApproved
+			request.Role = role
+			err = gorm.G[models.UsersFile](tx, clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "file_uuid"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"role": role, "expires_at": 0}),
+				Where:     clause.Where{Exprs: []clause.Expression{clause.Neq{Column: "users_files.role", Value: models.RoleOwner}}},
+			}).Create(ctx, &models.UsersFile{UserID: request.UserID, FileUUID: fileUUID, Role: role})
+			if err != nil {
+				return err
+			}
+		}
+		request.ResolvedAt = time.Now().Unix()
+
+		return tx.WithContext(ctx).Model(&models.AccessRequest{}).
+			Where("request_id = ?", request.RequestID).
+			Updates(map[string]interface{}{
+				"status":      request.Status,
+				"role":        request.Role,
+				"resolved_at": request.ResolvedAt,
+			}).Error
+	})
+	return request, err
+}
+
+// DeleteExpiredShares removes the accesses past their expiry and notifies the users,
+// which disconnects their live sessions from the file.
+func DeleteExpiredShares(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(time.Minute * 5)
+	defer ticker.Stop()
+
+	deleteExpiredShares(ctx, db)
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("DeleteExpiredShares stopped:", ctx.Err())
+			return
+
+		case <-ticker.C:
+			deleteExpiredShares(ctx, db)
+		}
+	}
+}
+
+func deleteExpiredShares(ctx context.Context, db *gorm.DB) {
+	expired, err := gorm.G[models.UsersFile](db).
+		Where("expires_at != 0").
+		Where("expires_at <= ?", time.Now().Unix()).
+		Find(ctx)
+	if err != nil {
+		fmt.Printf("Error finding expired shares: %v\n", err)
+		return
+	}
+
+	for _, share := range expired {
+		// the access may have been extended since it was read
+		rowsAffected, err := gorm.G[models.UsersFile](db).
+			Where("user_id = ?", share.UserID).
+			Where("file_uuid = ?", share.FileUUID).
+			Where("expires_at = ?", share.ExpiresAt).
+			Where("role != ?", models.RoleOwner).
+			Delete(ctx)
+		if err != nil {
+			fmt.Printf("Error deleting expired share: %v\n", err)
+			continue
+		}
+		if rowsAffected == 0 {
+			continue
+		}
+
+		// a group may still give access to the file
+		err = notificationUtils.NotifyAccessChanged(share.FileUUID, []uint32{share.UserID}, ctx, db)
+		if err != nil {
+			fmt.Printf("Error publishing expired share revoke: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package file
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func insertUser(t *testing.T, username string) uint32 {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	return user.UserID
+}
+
+func insertOwnedFile(t *testing.T, fileUUID string, ownerID uint32) {
+	require.NoError(t, gorm.G[models.File](testenv.DB).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: fileUUID}))
+	require.NoError(t, gorm.G[models.UsersFile](testenv.DB).Create(t.Context(), &models.UsersFile{UserID: ownerID, FileUUID: fileUUID, Role: models.RoleOwner}))
+}
+
+func accessOf(t *testing.T, userID uint32, fileUUID string) models.UsersFile {
+	access, err := gorm.G[models.UsersFile](testenv.DB).Where("user_id = ?", userID).Where("file_uuid = ?", fileUUID).First(t.Context())
+	require.NoError(t, err)
+	return access
+}
+
+func TestRedeemInvite(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	firstID := insertUser(t, "first")
+	secondID := insertUser(t, "second")
+	formerID := insertUser(t, "former")
+
+	invite, err := createInvite(t.Context(), db, fileUUID, ownerID, models.RoleCommenter, 0, 2)
+	require.NoError(t, err)
+
+	// CASE unknown token
+	_, _, err = redeemInvite(t.Context(), db, "unknown", firstID)
+	require.ErrorIs(t, err, ErrInviteNotFound)
+
+	// CASE the access is created with the role of the invite
+	redeemedFile, created, err := redeemInvite(t.Context(), db, invite.Token, firstID)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, fileUUID, redeemedFile)
+	require.Equal(t, models.RoleCommenter, accessOf(t, firstID, fileUUID).Role)
+
+	// CASE a member redeeming again keeps their access and doesn't consume a use
+	_, created, err = redeemInvite(t.Context(), db, invite.Token, firstID)
+	require.NoError(t, err)
+	require.False(t, created)
+	_, created, err = redeemInvite(t.Context(), db, invite.Token, ownerID)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, models.RoleOwner, accessOf(t, ownerID, fileUUID).Role)
+
+	// CASE an expired access is replaced, not taken as a membership
+	expired := models.UsersFile{UserID: formerID, FileUUID: fileUUID, Role: models.RoleViewer, ExpiresAt: time.Now().Unix() - 60}
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &expired))
+	_, created, err = redeemInvite(t.Context(), db, invite.Token, formerID)
+	require.NoError(t, err)
+	require.True(t, created)
+	access := accessOf(t, formerID, fileUUID)
+	require.Equal(t, models.RoleCommenter, access.Role)
+	require.Zero(t, access.ExpiresAt)
+
+	// CASE every use of the link is consumed
+	_, _, err = redeemInvite(t.Context(), db, invite.Token, secondID)
+	require.ErrorIs(t, err, ErrInviteExhausted)
+	stored, err := gorm.G[models.FileInvite](db).Where("invite_id = ?", invite.InviteID).First(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 2, stored.Uses)
+
+	// CASE expired link
+	invite, err = createInvite(t.Context(), db, fileUUID, ownerID, models.RoleViewer, 60, 0)
+	require.NoError(t, err)
+	_, err = gorm.G[models.FileInvite](db).Where("invite_id = ?", invite.InviteID).Update(t.Context(), "expires_at", time.Now().Unix()-1)
+	require.NoError(t, err)
+	_, _, err = redeemInvite(t.Context(), db, invite.Token, secondID)
+	require.ErrorIs(t, err, ErrInviteExpired)
+}
+
+func TestResolveAccessRequest(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	requesterID := insertUser(t, "requester")
+	deniedID := insertUser(t, "denied")
+
+	// CASE members have nothing to request, unknown files can't be requested
+	_, err := requestAccess(t.Context(), db, fileUUID, ownerID, "")
+	require.ErrorIs(t, err, ErrAlreadyMember)
+	_, err = requestAccess(t.Context(), db, "22222222-2222-2222-2222-222222222222", requesterID, "")
+	require.ErrorIs(t, err, ErrFindFile)
+
+	// CASE one pending request at a time
+	request, err := requestAccess(t.Context(), db, fileUUID, requesterID, "  let me in  ")
+	require.NoError(t, err)
+	require.Equal(t, "let me in", request.Message)
+	_, err = requestAccess(t.Context(), db, fileUUID, requesterID, "")
+	require.ErrorIs(t, err, ErrRequestPending)
+
+	// CASE approval with an invalid role
+	_, err = resolveAccessRequest(t.Context(), db, fileUUID, request.RequestID, models.RoleOwner)
+	require.ErrorIs(t, err, ErrInvalidShareRole)
+
+	// CASE approval replaces the access that expired in the meantime
+	expired := models.UsersFile{UserID: requesterID, FileUUID: fileUUID, Role: models.RoleViewer, ExpiresAt: time.Now().Unix() - 60}
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &expired))
+
+	resolved, err := resolveAccessRequest(t.Context(), db, fileUUID, request.RequestID, models.RoleCollaborator)
+	require.NoError(t, err)
+	require.Equal(t, models.AccessRequestApproved, resolved.Status)
+	require.Equal(t, models.RoleCollaborator, resolved.Role)
+	access := accessOf(t, requesterID, fileUUID)
+	require.Equal(t, models.RoleCollaborator, access.Role)
+	require.Zero(t, access.ExpiresAt)
+
+	// CASE a request is resolved once
+	_, err = resolveAccessRequest(t.Context(), db, fileUUID, request.RequestID, models.RoleViewer)
+	require.ErrorIs(t, err, ErrRequestNotFound)
+
+	// CASE denial gives no access
+	request, err = requestAccess(t.Context(), db, fileUUID, deniedID, "")
+	require.NoError(t, err)
+	resolved, err = resolveAccessRequest(t.Context(), db, fileUUID, request.RequestID, "")
+	require.NoError(t, err)
+	require.Equal(t, models.AccessRequestDenied, resolved.Status)
+	count, err := gorm.G[models.UsersFile](db).Where("user_id = ?", deniedID).Count(t.Context(), "user_id")
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestTransferOwnership(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	collaboratorID := insertUser(t, "collaborator")
+	expiredID := insertUser(t, "expired")
+	outsiderID := insertUser(t, "outsider")
+
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: collaboratorID, FileUUID: fileUUID, Role: models.RoleViewer, ExpiresAt: time.Now().Unix() + 3600}))
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: expiredID, FileUUID: fileUUID, Role: models.RoleCollaborator, ExpiresAt: time.Now().Unix() - 60}))
+
+	// CASE the caller isn't the owner
+	err := transferOwnership(t.Context(), db, fileUUID, collaboratorID, outsiderID)
+	require.ErrorIs(t, err, ErrNotOwner)
+
+	// CASE the target isn't a member, or their access expired
+	err = transferOwnership(t.Context(), db, fileUUID, ownerID, outsiderID)
+	require.ErrorIs(t, err, ErrNotMember)
+	err = transferOwnership(t.Context(), db, fileUUID, ownerID, expiredID)
+	require.ErrorIs(t, err, ErrNotMember)
+
+	// nothing changed
+	require.Equal(t, models.RoleOwner, accessOf(t, ownerID, fileUUID).Role)
+	require.Equal(t, models.RoleViewer, accessOf(t, collaboratorID, fileUUID).Role)
+
+	// CASE the new owner is promoted for good and the old one demoted
+	require.NoError(t, transferOwnership(t.Context(), db, fileUUID, ownerID, collaboratorID))
+	access := accessOf(t, collaboratorID, fileUUID)
+	require.Equal(t, models.RoleOwner, access.Role)
+	require.Zero(t, access.ExpiresAt)
+	require.Equal(t, models.RoleCollaborator, accessOf(t, ownerID, fileUUID).Role)
+
+	owners, err := gorm.G[models.UsersFile](db).Where("file_uuid = ?", fileUUID).Where("role = ?", models.RoleOwner).Count(t.Context(), "user_id")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), owners)
+
+	// CASE the previous owner can't transfer anymore
+	err = transferOwnership(t.Context(), db, fileUUID, ownerID, collaboratorID)
+	require.ErrorIs(t, err, ErrNotOwner)
+}
+
+func TestDeleteExpiredShares(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	ownerID := insertUser(t, "owner")
+	insertOwnedFile(t, fileUUID, ownerID)
+	expiredID := insertUser(t, "expired")
+	activeID := insertUser(t, "active")
+	permanentID := insertUser(t, "permanent")
+
+	now := time.Now().Unix()
+	for _, access := range []models.UsersFile{
+		{UserID: expiredID, FileUUID: fileUUID, Role: models.RoleCollaborator, ExpiresAt: now - 60},
+		{UserID: activeID, FileUUID: fileUUID, Role: models.RoleViewer, ExpiresAt: now + 3600},
+		{UserID: permanentID, FileUUID: fileUUID, Role: models.RoleCommenter},
+	} {
+		require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &access))
+	}
+	// an owner row is never removed, even with a past expiry
+	_, err := gorm.G[models.UsersFile](db).Where("user_id = ?", ownerID).Update(t.Context(), "expires_at", now-60)
+	require.NoError(t, err)
+
+	// CASE the expired access is refused before the sweeper runs
+	_, err = accessUtils.GetRole(expiredID, fileUUID, t.Context(), db)
+	require.ErrorIs(t, err, accessUtils.ErrNoAccess)
+	role, err := accessUtils.GetRole(activeID, fileUUID, t.Context(), db)
+	require.NoError(t, err)
+	require.Equal(t, models.RoleViewer, role)
+
+	// CASE the sweeper removes only the expired shares
+	deleteExpiredShares(t.Context(), db)
+
+	count, err := gorm.G[models.UsersFile](db).Where("user_id = ?", expiredID).Count(t.Context(), "user_id")
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Equal(t, models.RoleViewer, accessOf(t, activeID, fileUUID).Role)
+	require.Equal(t, models.RoleCommenter, accessOf(t, permanentID, fileUUID).Role)
+	require.Equal(t, models.RoleOwner, accessOf(t, ownerID, fileUUID).Role)
+
+	// CASE an access extended after it expired is kept
+	extended := models.UsersFile{UserID: expiredID, FileUUID: fileUUID, Role: models.RoleViewer, ExpiresAt: now - 60}
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &extended))
+	_, err = gorm.G[models.UsersFile](db).Where("user_id = ?", expiredID).Update(t.Context(), "expires_at", now+3600)
+	require.NoError(t, err)
+
+	deleteExpiredShares(t.Context(), db)
+	require.Equal(t, models.RoleViewer, accessOf(t, expiredID, fileUUID).Role)
+}
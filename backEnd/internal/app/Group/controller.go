@@ -0,0 +1,279 @@
+package group
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GroupData struct {
+	GroupID string `json:"group_id"`
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+}
+
+func CreateGroupController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	group, err := createGroup(ctx, db, authGuard.UserID(c), req.Name)
+	if errors.Is(err, ErrInvalidGroupName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't create group"})
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+func GetGroupsController(c *gin.Context, db *gorm.DB) {
+	userID := authGuard.UserID(c)
+
+	var groups []GroupData
+	err := db.Table("groups").
+		Select("groups.group_id, groups.name, group_members.role").
+		Joins("JOIN group_members ON groups.group_id = group_members.group_id").
+		Where("group_members.user_id = ?", userID).
+		Order("groups.name").
+		Scan(&groups).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func GetGroupMembersController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	groupID := c.Query("group_id")
+
+	_, err := groupRole(ctx, db, groupID, authGuard.UserID(c))
+	if errors.Is(err, ErrInvalidGroupID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrNotGroupMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+
+	var members []common.SharedUsers
+	err = db.Table("users").
+		Select("users.username, group_members.role").
+		Joins("JOIN group_members ON users.user_id = group_members.user_id").
+		Where("group_members.group_id = ?", groupID).
+		Scan(&members).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+func AddGroupMemberController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		GroupID  string `json:"group_id" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Role     string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.GroupRoleMember
+	}
+	if (&models.GroupMemberMigration{Role: req.Role}).Validate() != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidGroupRole.Error()})
+		return
+	}
+
+	if !requireGroupAdmin(c, db, req.GroupID) {
+		return
+	}
+
+	user, err := gorm.G[models.User](db).Where("username = ?", req.Username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err = gorm.G[models.GroupMember](db).Create(ctx, &models.GroupMember{GroupID: req.GroupID, UserID: user.UserID, Role: req.Role})
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this group"})
+		return
+	}
+
+	fileUUIDs, err := groupFileUUIDs(ctx, db, req.GroupID)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, fileUUID := range fileUUIDs {
+		err = notificationUtils.NotifyFileShared(fileUUID, []uint32{user.UserID}, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": "Member added successfully"})
+}
+
+func ChangeGroupMemberRoleController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		GroupID  string `json:"group_id" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Role     string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !requireGroupAdmin(c, db, req.GroupID) {
+		return
+	}
+
+	user, err := gorm.G[models.User](db).Where("username = ?", req.Username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err = updateMember(ctx, db, req.GroupID, user.UserID, req.Role)
+	if !handleMemberError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": "Role changed successfully"})
+}
+
+// RemoveGroupMemberController removes a member, admins remove anyone and members themselves.
+func RemoveGroupMemberController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	groupID := c.Query("group_id")
+	username := c.Query("username")
+
+	user, err := gorm.G[models.User](db).Where("username = ?", username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.UserID != authGuard.UserID(c) && !requireGroupAdmin(c, db, groupID) {
+		return
+	}
+
+	err = updateMember(ctx, db, groupID, user.UserID, "")
+	if !handleMemberError(c, err) {
+		return
+	}
+
+	fileUUIDs, err := groupFileUUIDs(ctx, db, groupID)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, fileUUID := range fileUUIDs {
+		err = notificationUtils.NotifyAccessChanged(fileUUID, []uint32{user.UserID}, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Member removed successfully"})
+}
+
+func DeleteGroupController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	groupID := c.Query("group_id")
+
+	if !requireGroupAdmin(c, db, groupID) {
+		return
+	}
+
+	memberIDs, err := accessUtils.GroupMemberIDs(groupID, ctx, db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	fileUUIDs, err := groupFileUUIDs(ctx, db, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+
+	_, err = gorm.G[models.Group](db).Where("group_id = ?", groupID).Delete(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+
+	for _, fileUUID := range fileUUIDs {
+		err = notificationUtils.NotifyAccessChanged(fileUUID, memberIDs, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// requireGroupAdmin answers 403 and returns false when the user isn't an admin of the group,
+// 400 when the group ID is malformed.
+func requireGroupAdmin(c *gin.Context, db *gorm.DB, groupID string) bool {
+	role, err := groupRole(c.Request.Context(), db, groupID, authGuard.UserID(c))
+	if errors.Is(err, ErrInvalidGroupID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil && !errors.Is(err, ErrNotGroupMember) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return false
+	}
+	if role != models.GroupRoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only a group admin can do this"})
+		return false
+	}
+	return true
+}
+
+// handleMemberError answers the errors of updateMember and returns false if there was one.
+func handleMemberError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrInvalidGroupID), errors.Is(err, ErrInvalidGroupRole), errors.Is(err, ErrLastAdmin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotGroupMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	}
+	return false
+}
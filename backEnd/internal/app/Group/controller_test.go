@@ -0,0 +1,221 @@
+package group_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	group "github.com/evanrmtl/miniDoc/internal/app/Group"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/middleware/subroute"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	setupTestRS256KeyPair()
+
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+	redisUtils.CreateRedis(context.Background())
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+func createTestRoute() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	subroute.CreateGroupRoutes(r.Group("/v1"), testenv.DB)
+	subroute.CreateFileRoutes(r.Group("/v1"), testenv.DB)
+	return r
+}
+
+// insertUser creates the user and returns their ID with a valid token.
+func insertUser(t *testing.T, username string) (uint32, string) {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	token, err := jwtUtils.CreateJWT(t.Context(), username, testenv.DB)
+	require.NoError(t, err)
+	return user.UserID, token
+}
+
+func request(router *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(writer, req)
+	return writer
+}
+
+func TestGroups(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	router := createTestRoute()
+
+	ownerID, ownerToken := insertUser(t, "owner")
+	_, adminToken := insertUser(t, "admin")
+	memberID, memberToken := insertUser(t, "member")
+	_, outsiderToken := insertUser(t, "outsider")
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	require.NoError(t, gorm.G[models.File](db).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: "shared"}))
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: ownerID, FileUUID: fileUUID, Role: models.RoleOwner}))
+
+	// CASE invalid name
+	writer := request(router, http.MethodPost, "/v1/group/create", adminToken, `{"name": "   "}`)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE the creator is the first admin
+	writer = request(router, http.MethodPost, "/v1/group/create", adminToken, `{"name": "team"}`)
+	require.Equal(t, http.StatusCreated, writer.Code)
+	var created models.Group
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &created))
+	groupID := created.GroupID
+
+	writer = request(router, http.MethodGet, "/v1/group/get", adminToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var groups []group.GroupData
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &groups))
+	require.Equal(t, []group.GroupData{{GroupID: groupID, Name: "team", Role: models.GroupRoleAdmin}}, groups)
+
+	// CASE only an admin adds members
+	addMember := func(token string, groupID string, username string, role string) int {
+		body := fmt.Sprintf(`{"group_id": "%s", "username": "%s", "role": "%s"}`, groupID, username, role)
+		return request(router, http.MethodPost, "/v1/group/addMember", token, body).Code
+	}
+	require.Equal(t, http.StatusForbidden, addMember(outsiderToken, groupID, "member", ""))
+	require.Equal(t, http.StatusBadRequest, addMember(adminToken, groupID, "member", models.RoleOwner))
+	require.Equal(t, http.StatusBadRequest, addMember(adminToken, "team", "member", ""))
+	require.Equal(t, http.StatusNotFound, addMember(adminToken, groupID, "unknown", ""))
+	require.Equal(t, http.StatusCreated, addMember(adminToken, groupID, "member", ""))
+	require.Equal(t, http.StatusConflict, addMember(adminToken, groupID, "member", ""))
+	require.Equal(t, http.StatusForbidden, addMember(memberToken, groupID, "outsider", ""))
+
+	// CASE listing the members
+	writer = request(router, http.MethodGet, "/v1/group/members?group_id="+groupID, memberToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var members []common.SharedUsers
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &members))
+	require.Len(t, members, 2)
+	writer = request(router, http.MethodGet, "/v1/group/members?group_id="+groupID, outsiderToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+	writer = request(router, http.MethodGet, "/v1/group/members?group_id=team", memberToken, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE sharing the file with the group
+	shareGroup := func(groupID string) int {
+		body := fmt.Sprintf(`{"file_uuid": "%s", "group_id": "%s", "role": "%s"}`, fileUUID, groupID, models.RoleCommenter)
+		return request(router, http.MethodPost, "/v1/file/shareGroup", ownerToken, body).Code
+	}
+	require.Equal(t, http.StatusBadRequest, shareGroup("team"))
+	require.Equal(t, http.StatusNotFound, shareGroup("33333333-3333-3333-3333-333333333333"))
+	require.Equal(t, http.StatusOK, shareGroup(groupID))
+
+	// the members get the role of the group share
+	role, err := accessUtils.GetRole(memberID, fileUUID, t.Context(), db)
+	require.NoError(t, err)
+	require.Equal(t, models.RoleCommenter, role)
+	_, err = accessUtils.GetRole(memberID, "22222222-2222-2222-2222-222222222222", t.Context(), db)
+	require.ErrorIs(t, err, accessUtils.ErrNoAccess)
+
+	// CASE a removed member loses the access of the group
+	removeMember := func(token string, groupID string, username string) int {
+		return request(router, http.MethodDelete, "/v1/group/removeMember?group_id="+groupID+"&username="+username, token, "").Code
+	}
+	require.Equal(t, http.StatusBadRequest, removeMember(memberToken, "team", "member"))
+	require.Equal(t, http.StatusOK, removeMember(adminToken, groupID, "member"))
+	require.Equal(t, http.StatusNotFound, removeMember(adminToken, groupID, "member"))
+	_, err = accessUtils.GetRole(memberID, fileUUID, t.Context(), db)
+	require.ErrorIs(t, err, accessUtils.ErrNoAccess)
+
+	// CASE removing the group share removes the access of its members
+	require.Equal(t, http.StatusCreated, addMember(adminToken, groupID, "member", ""))
+	_, err = accessUtils.GetRole(memberID, fileUUID, t.Context(), db)
+	require.NoError(t, err)
+
+	removeGroup := func(groupID string) int {
+		return request(router, http.MethodDelete, "/v1/file/removeGroup?file_uuid="+fileUUID+"&group_id="+groupID, ownerToken, "").Code
+	}
+	require.Equal(t, http.StatusBadRequest, removeGroup("team"))
+	require.Equal(t, http.StatusOK, removeGroup(groupID))
+	require.Equal(t, http.StatusNotFound, removeGroup(groupID))
+	_, err = accessUtils.GetRole(memberID, fileUUID, t.Context(), db)
+	require.ErrorIs(t, err, accessUtils.ErrNoAccess)
+
+	// CASE the last admin can't leave nor be demoted
+	changeRole := func(token string, username string, role string) int {
+		body := fmt.Sprintf(`{"group_id": "%s", "username": "%s", "role": "%s"}`, groupID, username, role)
+		return request(router, http.MethodPut, "/v1/group/changeMemberRole", token, body).Code
+	}
+	require.Equal(t, http.StatusBadRequest, removeMember(adminToken, groupID, "admin"))
+	require.Equal(t, http.StatusBadRequest, changeRole(adminToken, "admin", models.GroupRoleMember))
+
+	// once another member is promoted, the admin leaves
+	require.Equal(t, http.StatusForbidden, changeRole(memberToken, "member", models.GroupRoleAdmin))
+	require.Equal(t, http.StatusOK, changeRole(adminToken, "member", models.GroupRoleAdmin))
+	require.Equal(t, http.StatusOK, removeMember(adminToken, groupID, "admin"))
+
+	// CASE deleting the group
+	writer = request(router, http.MethodDelete, "/v1/group/delete?group_id=team", memberToken, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	writer = request(router, http.MethodDelete, "/v1/group/delete?group_id="+groupID, adminToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+	writer = request(router, http.MethodDelete, "/v1/group/delete?group_id="+groupID, memberToken, "")
+	require.Equal(t, http.StatusNoContent, writer.Code)
+
+	count, err := gorm.G[models.GroupMember](db).Where("group_id = ?", groupID).Count(t.Context(), "*")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+}
+
+func setupTestRS256KeyPair() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  privateRSA key: %v", err))
+	}
+
+	privateKeyPEM := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, privateKeyPEM)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  publicRSA key: %v", err))
+	}
+	publicKeyPEM := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	}
+	var publicKeyBuf bytes.Buffer
+	pem.Encode(&publicKeyBuf, publicKeyPEM)
+
+	os.Setenv("RS256_PRIVATE_KEY", privateKeyBuf.String())
+	os.Setenv("RS256_PUBLIC_KEY", publicKeyBuf.String())
+}
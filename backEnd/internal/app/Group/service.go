@@ -0,0 +1,108 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidGroupID   = errors.New("group ID must be a UUID")
+	ErrInvalidGroupName = errors.New("group name must be between 1 and 50 characters")
+	ErrInvalidGroupRole = errors.New("role must be Admin or Member")
+	ErrNotGroupMember   = errors.New("user is not a member of this group")
+	ErrLastAdmin        = errors.New("a group must keep at least one admin")
+)
+
+// groupRole returns the role of the user in the group, ErrNotGroupMember if they aren't in it.
+func groupRole(ctx context.Context, db *gorm.DB, groupID string, userID uint32) (string, error) {
+	if uuid.Validate(groupID) != nil {
+		return "", ErrInvalidGroupID
+	}
+	member, err := gorm.G[models.GroupMember](db).Where("group_id = ?", groupID).Where("user_id = ?", userID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotGroupMember
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// createGroup creates the group with its creator as first admin.
+func createGroup(ctx context.Context, db *gorm.DB, userID uint32, name string) (models.Group, error) {
+	var group models.Group
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return group, ErrInvalidGroupName
+	}
+
+	group = models.Group{
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: time.Now().Unix(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := gorm.G[models.Group](tx).Create(ctx, &group)
+		if err != nil {
+			return err
+		}
+		return gorm.G[models.GroupMember](tx).Create(ctx, &models.GroupMember{GroupID: group.GroupID, UserID: userID, Role: models.GroupRoleAdmin})
+	})
+	return group, err
+}
+
+// updateMember changes the role of a member or removes them when role is empty.
+// The admins are locked so two admins can't demote each other at the same time
+// and leave the group without any.
+func updateMember(ctx context.Context, db *gorm.DB, groupID string, userID uint32, role string) error {
+	if uuid.Validate(groupID) != nil {
+		return ErrInvalidGroupID
+	}
+	if role != "" && (&models.GroupMemberMigration{Role: role}).Validate() != nil {
+		return ErrInvalidGroupRole
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		admins, err := gorm.G[models.GroupMember](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("group_id = ?", groupID).
+			Where("role = ?", models.GroupRoleAdmin).
+			Find(ctx)
+		if err != nil {
+			return err
+		}
+		if role != models.GroupRoleAdmin && len(admins) == 1 && admins[0].UserID == userID {
+			return ErrLastAdmin
+		}
+
+		var rowsAffected int
+		if role == "" {
+			rowsAffected, err = gorm.G[models.GroupMember](tx).Where("group_id = ?", groupID).Where("user_id = ?", userID).Delete(ctx)
+		} else {
+			rowsAffected, err = gorm.G[models.GroupMember](tx).Where("group_id = ?", groupID).Where("user_id = ?", userID).Update(ctx, "role", role)
+		}
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotGroupMember
+		}
+		return nil
+	})
+}
+
+// groupFileUUIDs returns the files shared with the group.
+func groupFileUUIDs(ctx context.Context, db *gorm.DB, groupID string) ([]string, error) {
+	var fileUUIDs []string
+	err := db.WithContext(ctx).Model(&models.GroupFile{}).
+		Where("group_id = ?", groupID).
+		Pluck("file_uuid", &fileUUIDs).Error
+	return fileUUIDs, err
+}
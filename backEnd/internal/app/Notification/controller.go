@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNotificationsController lists the inbox of the user, newest first.
+// Query: page (from 1), limit (up to 100) and unread=true to skip the read notifications.
+func GetNotificationsController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPage.Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidPage.Error()})
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	result, err := listNotifications(ctx, db, authGuard.UserID(c), page, limit, unreadOnly)
+	if errors.Is(err, ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func MarkReadController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		NotificationID string `json:"notification_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err := markRead(ctx, db, authGuard.UserID(c), req.NotificationID)
+	if errors.Is(err, ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't mark notification as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": "Notification marked as read"})
+}
+
+func MarkAllReadController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	marked, err := markAllRead(ctx, db, authGuard.UserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't mark notifications as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
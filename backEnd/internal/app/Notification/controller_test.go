@@ -0,0 +1,206 @@
+package notification_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	notification "github.com/evanrmtl/miniDoc/internal/app/Notification"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/middleware/subroute"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	setupTestRS256KeyPair()
+
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+func createTestRoute() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	subroute.CreateNotificationRoutes(r.Group("/v1"), testenv.DB)
+	return r
+}
+
+// insertUser creates the user and returns their ID with a valid token.
+func insertUser(t *testing.T, username string) (uint32, string) {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	token, err := jwtUtils.CreateJWT(t.Context(), username, testenv.DB)
+	require.NoError(t, err)
+	return user.UserID, token
+}
+
+// insertNotifications stores count notifications of the user, the i-th one created at second i.
+func insertNotifications(t *testing.T, userID uint32, count int) []string {
+	ids := make([]string, count)
+	for i := range count {
+		stored := models.Notification{
+			UserID:           userID,
+			NotificationType: "file_shared",
+			Payload:          fmt.Sprintf(`{"index": %d}`, i),
+			CreatedAt:        int64(i + 1),
+		}
+		require.NoError(t, gorm.G[models.Notification](testenv.DB).Create(t.Context(), &stored))
+		ids[i] = stored.NotificationID
+	}
+	return ids
+}
+
+func request(router *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(writer, req)
+	return writer
+}
+
+func list(t *testing.T, router *gin.Engine, token string, query string) notification.Page {
+	writer := request(router, http.MethodGet, "/v1/notification/list"+query, token, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var page notification.Page
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &page))
+	return page
+}
+
+func TestGetNotifications(t *testing.T) {
+	testenv.CleanTables()
+	router := createTestRoute()
+
+	userID, token := insertUser(t, "reader")
+	otherID, _ := insertUser(t, "other")
+	ids := insertNotifications(t, userID, 5)
+	insertNotifications(t, otherID, 2)
+
+	// CASE pages of the inbox, newest first
+	page := list(t, router, token, "?page=1&limit=2")
+	require.Equal(t, int64(5), page.Total)
+	require.Equal(t, int64(5), page.Unread)
+	require.Len(t, page.Notifications, 2)
+	require.Equal(t, ids[4], page.Notifications[0].NotificationID)
+	require.Equal(t, ids[3], page.Notifications[1].NotificationID)
+
+	page = list(t, router, token, "?page=3&limit=2")
+	require.Len(t, page.Notifications, 1)
+	require.Equal(t, ids[0], page.Notifications[0].NotificationID)
+
+	page = list(t, router, token, "?page=4&limit=2")
+	require.Empty(t, page.Notifications)
+
+	// CASE only the unread ones
+	require.NoError(t, testenv.DB.Model(&models.Notification{}).Where("notification_id IN ?", ids[3:]).Update("read", true).Error)
+	page = list(t, router, token, "?unread=true")
+	require.Equal(t, int64(3), page.Total)
+	require.Equal(t, int64(3), page.Unread)
+	require.Len(t, page.Notifications, 3)
+	require.Equal(t, ids[2], page.Notifications[0].NotificationID)
+
+	// CASE invalid pages
+	writer := request(router, http.MethodGet, "/v1/notification/list?page=0", token, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	writer = request(router, http.MethodGet, "/v1/notification/list?limit=101", token, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	writer = request(router, http.MethodGet, "/v1/notification/list?page=first", token, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+}
+
+func TestMarkRead(t *testing.T) {
+	testenv.CleanTables()
+	router := createTestRoute()
+
+	userID, token := insertUser(t, "reader")
+	otherID, otherToken := insertUser(t, "other")
+	ids := insertNotifications(t, userID, 3)
+	otherIDs := insertNotifications(t, otherID, 2)
+
+	// CASE mark one notification, twice
+	body := fmt.Sprintf(`{"notification_id": "%s"}`, ids[1])
+	writer := request(router, http.MethodPut, "/v1/notification/read", token, body)
+	require.Equal(t, http.StatusOK, writer.Code)
+	writer = request(router, http.MethodPut, "/v1/notification/read", token, body)
+	require.Equal(t, http.StatusOK, writer.Code)
+
+	page := list(t, router, token, "")
+	require.Equal(t, int64(2), page.Unread)
+	require.True(t, page.Notifications[1].Read)
+
+	// CASE the notification of another user
+	body = fmt.Sprintf(`{"notification_id": "%s"}`, otherIDs[0])
+	writer = request(router, http.MethodPut, "/v1/notification/read", token, body)
+	require.Equal(t, http.StatusNotFound, writer.Code)
+	require.Equal(t, int64(2), list(t, router, otherToken, "").Unread)
+
+	// CASE missing notification
+	writer = request(router, http.MethodPut, "/v1/notification/read", token, `{}`)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE mark all, only the ones of the user and still unread
+	writer = request(router, http.MethodPut, "/v1/notification/readAll", token, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var marked struct {
+		Marked int `json:"marked"`
+	}
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &marked))
+	require.Equal(t, 2, marked.Marked)
+
+	require.Equal(t, int64(0), list(t, router, token, "").Unread)
+	require.Equal(t, int64(2), list(t, router, otherToken, "").Unread)
+
+	writer = request(router, http.MethodPut, "/v1/notification/readAll", token, "")
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &marked))
+	require.Equal(t, 0, marked.Marked)
+}
+
+func setupTestRS256KeyPair() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  privateRSA key: %v", err))
+	}
+
+	privateKeyPEM := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, privateKeyPEM)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  publicRSA key: %v", err))
+	}
+	publicKeyPEM := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	}
+	var publicKeyBuf bytes.Buffer
+	pem.Encode(&publicKeyBuf, publicKeyPEM)
+
+	os.Setenv("RS256_PRIVATE_KEY", privateKeyBuf.String())
+	os.Setenv("RS256_PUBLIC_KEY", publicKeyBuf.String())
+}
@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"context"
+	"errors"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	ErrInvalidPage          = errors.New("page must be at least 1 and limit between 1 and 100")
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// Page is a page of the inbox, newest first. Unread counts the whole inbox.
+type Page struct {
+	Notifications []common.UserNotification `json:"notifications"`
+	Page          int                       `json:"page"`
+	Limit         int                       `json:"limit"`
+	Total         int64                     `json:"total"`
+	Unread        int64                     `json:"unread"`
+}
+
+// listNotifications returns the page of the inbox of the user, pages start at 1.
+// With unreadOnly the read notifications are skipped.
+func listNotifications(ctx context.Context, db *gorm.DB, userID uint32, page int, limit int, unreadOnly bool) (Page, error) {
+	result := Page{Page: page, Limit: limit, Notifications: []common.UserNotification{}}
+	if page < 1 || limit < 1 || limit > maxPageSize {
+		return result, ErrInvalidPage
+	}
+
+	query := gorm.G[models.Notification](db).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read = ?", false)
+	}
+
+	var err error
+	result.Total, err = query.Count(ctx, "notification_id")
+	if err != nil {
+		return result, err
+	}
+	result.Unread, err = gorm.G[models.Notification](db).Where("user_id = ?", userID).Where("read = ?", false).Count(ctx, "notification_id")
+	if err != nil {
+		return result, err
+	}
+
+	stored, err := query.Order("created_at desc").Order("notification_id").Offset((page - 1) * limit).Limit(limit).Find(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, notification := range stored {
+		result.Notifications = append(result.Notifications, notificationUtils.FromModel(notification))
+	}
+	return result, nil
+}
+
+// markRead marks one notification of the user as read, reading it again is not an error.
+func markRead(ctx context.Context, db *gorm.DB, userID uint32, notificationID string) error {
+	count, err := gorm.G[models.Notification](db).
+		Where("notification_id = ?", notificationID).
+		Where("user_id = ?", userID).
+		Count(ctx, "notification_id")
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotificationNotFound
+	}
+
+	_, err = gorm.G[models.Notification](db).
+		Where("notification_id = ?", notificationID).
+		Where("user_id = ?", userID).
+		Update(ctx, "read", true)
+	return err
+}
+
+// markAllRead marks every unread notification of the user as read and returns how many were.
+func markAllRead(ctx context.Context, db *gorm.DB, userID uint32) (int, error) {
+	return gorm.G[models.Notification](db).
+		Where("user_id = ?", userID).
+		Where("read = ?", false).
+		Update(ctx, "read", true)
+}
@@ -0,0 +1,248 @@
+package workspace
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceData struct {
+	WorkspaceID string `json:"workspace_id"`
+	Name        string `json:"name"`
+	DefaultRole string `json:"default_role"`
+	Role        string `json:"role"`
+}
+
+func CreateWorkspaceController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		DefaultRole string `json:"default_role"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	workspace, err := createWorkspace(ctx, db, authGuard.UserID(c), req.Name, req.DefaultRole)
+	if errors.Is(err, ErrInvalidWorkspaceName) || errors.Is(err, ErrInvalidDefaultRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't create workspace"})
+		return
+	}
+	c.JSON(http.StatusCreated, workspace)
+}
+
+func GetWorkspacesController(c *gin.Context, db *gorm.DB) {
+	userID := authGuard.UserID(c)
+
+	var workspaces []WorkspaceData
+	err := db.Table("workspaces").
+		Select("workspaces.workspace_id, workspaces.name, workspaces.default_role, workspace_members.role").
+		Joins("JOIN workspace_members ON workspaces.workspace_id = workspace_members.workspace_id").
+		Where("workspace_members.user_id = ?", userID).
+		Order("workspaces.name").
+		Scan(&workspaces).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func GetWorkspaceMembersController(c *gin.Context, db *gorm.DB) {
+	workspaceID := c.Query("workspace_id")
+
+	if !requireWorkspaceMember(c, db, workspaceID) {
+		return
+	}
+
+	var members []common.SharedUsers
+	err := db.Table("users").
+		Select("users.username, workspace_members.role").
+		Joins("JOIN workspace_members ON users.user_id = workspace_members.user_id").
+		Where("workspace_members.workspace_id = ?", workspaceID).
+		Scan(&members).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// AddWorkspaceMemberController invites a user in the workspace, they get the default role
+// of the workspace on all its files.
+func AddWorkspaceMemberController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	var req struct {
+		WorkspaceID string `json:"workspace_id" binding:"required"`
+		Username    string `json:"username" binding:"required"`
+		Role        string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.WorkspaceRoleMember
+	}
+	if (&models.WorkspaceMemberMigration{Role: req.Role}).Validate() != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidWorkspaceRole.Error()})
+		return
+	}
+
+	if !requireWorkspaceAdmin(c, db, req.WorkspaceID) {
+		return
+	}
+
+	workspace, err := gorm.G[models.Workspace](db).Where("workspace_id = ?", req.WorkspaceID).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	user, err := gorm.G[models.User](db).Where("username = ?", req.Username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err = gorm.G[models.WorkspaceMember](db).Create(ctx, &models.WorkspaceMember{WorkspaceID: req.WorkspaceID, UserID: user.UserID, Role: req.Role})
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this workspace"})
+		return
+	}
+
+	err = notificationUtils.NotifyWorkspaceJoined(workspace, user.UserID, req.Role, ctx, db)
+	if err != nil {
+		log.Println(err)
+	}
+
+	fileUUIDs, err := workspaceFileUUIDs(ctx, db, req.WorkspaceID)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, fileUUID := range fileUUIDs {
+		err = notificationUtils.NotifyFileShared(fileUUID, []uint32{user.UserID}, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": "Member added successfully"})
+}
+
+// RemoveWorkspaceMemberController removes a member, admins remove anyone and members themselves.
+func RemoveWorkspaceMemberController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	workspaceID := c.Query("workspace_id")
+	username := c.Query("username")
+
+	user, err := gorm.G[models.User](db).Where("username = ?", username).First(ctx)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.UserID != authGuard.UserID(c) && !requireWorkspaceAdmin(c, db, workspaceID) {
+		return
+	}
+
+	err = removeMember(ctx, db, workspaceID, user.UserID)
+	switch {
+	case errors.Is(err, ErrInvalidWorkspaceID), errors.Is(err, ErrLastAdmin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNotWorkspaceMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return
+	}
+
+	fileUUIDs, err := workspaceFileUUIDs(ctx, db, workspaceID)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, fileUUID := range fileUUIDs {
+		err = notificationUtils.NotifyAccessChanged(fileUUID, []uint32{user.UserID}, ctx, db)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": "Member removed successfully"})
+}
+
+func GetWorkspaceFilesController(c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+	workspaceID := c.Query("workspace_id")
+
+	if !requireWorkspaceMember(c, db, workspaceID) {
+		return
+	}
+
+	files, err := gorm.G[models.File](db).
+		Select("file_uuid", "file_name", "file_updated_at", "workspace_id").
+		Where("workspace_id = ?", workspaceID).
+		Order("file_updated_at desc").
+		Find(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while finding files"})
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// requireWorkspaceMember answers 403 and returns false when the user isn't in the workspace,
+// 400 when the workspace ID is malformed.
+func requireWorkspaceMember(c *gin.Context, db *gorm.DB, workspaceID string) bool {
+	_, err := workspaceRole(c.Request.Context(), db, workspaceID, authGuard.UserID(c))
+	if errors.Is(err, ErrInvalidWorkspaceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if errors.Is(err, ErrNotWorkspaceMember) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this workspace"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return false
+	}
+	return true
+}
+
+// requireWorkspaceAdmin answers 403 and returns false when the user isn't an admin of the workspace,
+// 400 when the workspace ID is malformed.
+func requireWorkspaceAdmin(c *gin.Context, db *gorm.DB, workspaceID string) bool {
+	role, err := workspaceRole(c.Request.Context(), db, workspaceID, authGuard.UserID(c))
+	if errors.Is(err, ErrInvalidWorkspaceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil && !errors.Is(err, ErrNotWorkspaceMember) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		return false
+	}
+	if role != models.WorkspaceRoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only a workspace admin can do this"})
+		return false
+	}
+	return true
+}
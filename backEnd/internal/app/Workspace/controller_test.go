@@ -0,0 +1,208 @@
+package workspace_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	workspace "github.com/evanrmtl/miniDoc/internal/app/Workspace"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/middleware/subroute"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	setupTestRS256KeyPair()
+
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+	redisUtils.CreateRedis(context.Background())
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+func createTestRoute() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	subroute.CreateWorkspaceRoutes(r.Group("/v1"), testenv.DB)
+	subroute.CreateFileRoutes(r.Group("/v1"), testenv.DB)
+	return r
+}
+
+// insertUser creates the user and returns their ID with a valid token.
+func insertUser(t *testing.T, username string) (uint32, string) {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	token, err := jwtUtils.CreateJWT(t.Context(), username, testenv.DB)
+	require.NoError(t, err)
+	return user.UserID, token
+}
+
+func request(router *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(writer, req)
+	return writer
+}
+
+func fileUUIDsOf(t *testing.T, writer *httptest.ResponseRecorder) []string {
+	require.Equal(t, http.StatusOK, writer.Code)
+	var files []models.File
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &files))
+	fileUUIDs := make([]string, 0, len(files))
+	for _, file := range files {
+		fileUUIDs = append(fileUUIDs, file.FileUUID)
+	}
+	return fileUUIDs
+}
+
+func TestWorkspace(t *testing.T) {
+	testenv.CleanTables()
+	router := createTestRoute()
+
+	adminID, adminToken := insertUser(t, "admin")
+	_, memberToken := insertUser(t, "member")
+	readerID, _ := insertUser(t, "reader")
+	_, outsiderToken := insertUser(t, "outsider")
+
+	// CASE invalid workspaces
+	writer := request(router, http.MethodPost, "/v1/workspace/create", adminToken, `{"name": "   "}`)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	writer = request(router, http.MethodPost, "/v1/workspace/create", adminToken, `{"name": "team", "default_role": "Owner"}`)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE the creator is the first admin
+	writer = request(router, http.MethodPost, "/v1/workspace/create", adminToken, `{"name": "team", "default_role": "Commenter"}`)
+	require.Equal(t, http.StatusCreated, writer.Code)
+	var created models.Workspace
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &created))
+	workspaceID := created.WorkspaceID
+
+	writer = request(router, http.MethodGet, "/v1/workspace/get", adminToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	var workspaces []workspace.WorkspaceData
+	require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &workspaces))
+	require.Equal(t, []workspace.WorkspaceData{{WorkspaceID: workspaceID, Name: "team", DefaultRole: models.RoleCommenter, Role: models.WorkspaceRoleAdmin}}, workspaces)
+
+	// CASE only an admin adds members
+	addMember := func(token string, username string, role string) int {
+		body := fmt.Sprintf(`{"workspace_id": "%s", "username": "%s", "role": "%s"}`, workspaceID, username, role)
+		return request(router, http.MethodPost, "/v1/workspace/addMember", token, body).Code
+	}
+	require.Equal(t, http.StatusForbidden, addMember(outsiderToken, "member", ""))
+	require.Equal(t, http.StatusBadRequest, addMember(adminToken, "member", models.RoleOwner))
+	require.Equal(t, http.StatusNotFound, addMember(adminToken, "unknown", ""))
+	require.Equal(t, http.StatusCreated, addMember(adminToken, "member", ""))
+	require.Equal(t, http.StatusConflict, addMember(adminToken, "member", ""))
+	require.Equal(t, http.StatusCreated, addMember(adminToken, "reader", ""))
+	require.Equal(t, http.StatusForbidden, addMember(memberToken, "outsider", ""))
+	writer = request(router, http.MethodPost, "/v1/workspace/addMember", adminToken, `{"workspace_id": "team", "username": "outsider"}`)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE a member creates files in the workspace, an outsider can't
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	writer = request(router, http.MethodPost, "/v1/file/create", memberToken, fmt.Sprintf(`{"file_uuid": "%s", "workspace_id": "%s"}`, fileUUID, workspaceID))
+	require.Equal(t, http.StatusCreated, writer.Code)
+	writer = request(router, http.MethodPost, "/v1/file/create", outsiderToken, fmt.Sprintf(`{"file_uuid": "%s", "workspace_id": "%s"}`, "22222222-2222-2222-2222-222222222222", workspaceID))
+	require.Equal(t, http.StatusForbidden, writer.Code)
+	writer = request(router, http.MethodPost, "/v1/file/create", memberToken, fmt.Sprintf(`{"file_uuid": "%s", "workspace_id": "team"}`, "22222222-2222-2222-2222-222222222222"))
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	personalUUID := "33333333-3333-3333-3333-333333333333"
+	writer = request(router, http.MethodPost, "/v1/file/create", adminToken, fmt.Sprintf(`{"file_uuid": "%s"}`, personalUUID))
+	require.Equal(t, http.StatusCreated, writer.Code)
+
+	// CASE the other members get the default role of the workspace on its files
+	role, err := accessUtils.GetRole(adminID, fileUUID, t.Context(), testenv.DB)
+	require.NoError(t, err)
+	require.Equal(t, models.RoleCommenter, role)
+	writer = request(router, http.MethodGet, "/v1/file/content?file_uuid="+fileUUID, adminToken, "")
+	require.Equal(t, http.StatusOK, writer.Code)
+	writer = request(router, http.MethodGet, "/v1/file/content?file_uuid="+fileUUID, outsiderToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+
+	// CASE listing the files of the workspace
+	require.Equal(t, []string{fileUUID}, fileUUIDsOf(t, request(router, http.MethodGet, "/v1/workspace/files?workspace_id="+workspaceID, adminToken, "")))
+	writer = request(router, http.MethodGet, "/v1/workspace/files?workspace_id="+workspaceID, outsiderToken, "")
+	require.Equal(t, http.StatusForbidden, writer.Code)
+
+	require.ElementsMatch(t, []string{fileUUID, personalUUID}, fileUUIDsOf(t, request(router, http.MethodGet, "/v1/file/get", adminToken, "")))
+	require.Equal(t, []string{fileUUID}, fileUUIDsOf(t, request(router, http.MethodGet, "/v1/file/get?workspace_id="+workspaceID, adminToken, "")))
+
+	// CASE malformed workspace IDs
+	for _, path := range []string{"/v1/workspace/files", "/v1/workspace/members", "/v1/file/get"} {
+		writer = request(router, http.MethodGet, path+"?workspace_id=team", adminToken, "")
+		require.Equal(t, http.StatusBadRequest, writer.Code, path)
+	}
+
+	// CASE removing members
+	removeMember := func(token string, username string) int {
+		return request(router, http.MethodDelete, "/v1/workspace/removeMember?workspace_id="+workspaceID+"&username="+username, token, "").Code
+	}
+	require.Equal(t, http.StatusForbidden, removeMember(memberToken, "reader"))
+	writer = request(router, http.MethodDelete, "/v1/workspace/removeMember?workspace_id=team&username=member", memberToken, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	require.Equal(t, http.StatusBadRequest, removeMember(adminToken, "admin"))
+	require.Equal(t, http.StatusOK, removeMember(adminToken, "reader"))
+	require.Equal(t, http.StatusNotFound, removeMember(adminToken, "reader"))
+
+	// the removed member loses the inherited access
+	_, err = accessUtils.GetRole(readerID, fileUUID, t.Context(), testenv.DB)
+	require.ErrorIs(t, err, accessUtils.ErrNoAccess)
+
+	// a member leaves by themselves
+	require.Equal(t, http.StatusOK, removeMember(memberToken, "member"))
+}
+
+func setupTestRS256KeyPair() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  privateRSA key: %v", err))
+	}
+
+	privateKeyPEM := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, privateKeyPEM)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  publicRSA key: %v", err))
+	}
+	publicKeyPEM := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	}
+	var publicKeyBuf bytes.Buffer
+	pem.Encode(&publicKeyBuf, publicKeyPEM)
+
+	os.Setenv("RS256_PRIVATE_KEY", privateKeyBuf.String())
+	os.Setenv("RS256_PUBLIC_KEY", publicKeyBuf.String())
+}
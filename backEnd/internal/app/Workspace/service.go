@@ -0,0 +1,108 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidWorkspaceID   = errors.New("workspace ID must be a UUID")
+	ErrInvalidWorkspaceName = errors.New("workspace name must be between 1 and 50 characters")
+	ErrInvalidWorkspaceRole = errors.New("role must be Admin or Member")
+	ErrInvalidDefaultRole   = errors.New("default role must be Collaborator, Commenter or Viewer")
+	ErrNotWorkspaceMember   = errors.New("user is not a member of this workspace")
+	ErrLastAdmin            = errors.New("a workspace must keep at least one admin")
+)
+
+// workspaceRole returns the role of the user in the workspace, ErrNotWorkspaceMember if they aren't in it.
+func workspaceRole(ctx context.Context, db *gorm.DB, workspaceID string, userID uint32) (string, error) {
+	if uuid.Validate(workspaceID) != nil {
+		return "", ErrInvalidWorkspaceID
+	}
+	member, err := gorm.G[models.WorkspaceMember](db).Where("workspace_id = ?", workspaceID).Where("user_id = ?", userID).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotWorkspaceMember
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// createWorkspace creates the workspace with its creator as first admin.
+// The members get defaultRole on its files, Collaborator when empty.
+func createWorkspace(ctx context.Context, db *gorm.DB, userID uint32, name string, defaultRole string) (models.Workspace, error) {
+	var workspace models.Workspace
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return workspace, ErrInvalidWorkspaceName
+	}
+	if defaultRole == "" {
+		defaultRole = models.RoleCollaborator
+	}
+	if !accessUtils.IsShareRole(defaultRole) {
+		return workspace, ErrInvalidDefaultRole
+	}
+
+	workspace = models.Workspace{
+		Name:        name,
+		DefaultRole: defaultRole,
+		CreatedBy:   userID,
+		CreatedAt:   time.Now().Unix(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := gorm.G[models.Workspace](tx).Create(ctx, &workspace)
+		if err != nil {
+			return err
+		}
+		return gorm.G[models.WorkspaceMember](tx).Create(ctx, &models.WorkspaceMember{WorkspaceID: workspace.WorkspaceID, UserID: userID, Role: models.WorkspaceRoleAdmin})
+	})
+	return workspace, err
+}
+
+// removeMember removes the user from the workspace. The admins are locked so the last
+// two admins can't leave at the same time.
+func removeMember(ctx context.Context, db *gorm.DB, workspaceID string, userID uint32) error {
+	if uuid.Validate(workspaceID) != nil {
+		return ErrInvalidWorkspaceID
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		admins, err := gorm.G[models.WorkspaceMember](tx, clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_id = ?", workspaceID).
+			Where("role = ?", models.WorkspaceRoleAdmin).
+			Find(ctx)
+		if err != nil {
+			return err
+		}
+		if len(admins) == 1 && admins[0].UserID == userID {
+			return ErrLastAdmin
+		}
+
+		rowsAffected, err := gorm.G[models.WorkspaceMember](tx).Where("workspace_id = ?", workspaceID).Where("user_id = ?", userID).Delete(ctx)
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrNotWorkspaceMember
+		}
+		return nil
+	})
+}
+
+// workspaceFileUUIDs returns the files owned by the workspace.
+func workspaceFileUUIDs(ctx context.Context, db *gorm.DB, workspaceID string) ([]string, error) {
+	var fileUUIDs []string
+	err := db.WithContext(ctx).Model(&models.File{}).
+		Where("workspace_id = ?", workspaceID).
+		Pluck("file_uuid", &fileUUIDs).Error
+	return fileUUIDs, err
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/lseqUtils"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -70,6 +71,20 @@ func GenerateDB() *gorm.DB {
 		&models.SessionMigration{},
 		&models.UsersFileMigration{},
 		&models.FilesContentsMigration{},
+		&models.DocumentOperationMigration{},
+		&models.DocumentSnapshotMigration{},
+		&models.FileVersionMigration{},
+		&models.FileInviteMigration{},
+		&models.GroupMigration{},
+		&models.GroupMemberMigration{},
+		&models.GroupFileMigration{},
+		&models.WorkspaceMigration{},
+		&models.WorkspaceMemberMigration{},
+		&models.AccessRequestMigration{},
+		&models.NotificationMigration{},
+		&models.CommentThreadMigration{},
+		&models.CommentMigration{},
+		&models.SuggestionMigration{},
 	)
 	if err != nil {
 		log.Fatalln("error when migrating models")
@@ -95,7 +110,130 @@ func GenerateDB() *gorm.DB {
 		log.Printf("Warning: constraint fk_files_contents_files_uuid already exist or error while creating it : %v", err)
 	}
 
+	err = db.Exec("ALTER TABLE document_operations ADD CONSTRAINT fk_document_operations_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_document_operations_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE document_snapshots ADD CONSTRAINT fk_document_snapshots_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_document_snapshots_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE file_versions ADD CONSTRAINT fk_file_versions_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_file_versions_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE file_invites ADD CONSTRAINT fk_file_invites_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_file_invites_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE group_members ADD CONSTRAINT fk_group_members_group_id FOREIGN KEY (group_id) REFERENCES groups(group_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_members_group_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE group_members ADD CONSTRAINT fk_group_members_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_members_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE group_files ADD CONSTRAINT fk_group_files_group_id FOREIGN KEY (group_id) REFERENCES groups(group_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_files_group_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE group_files ADD CONSTRAINT fk_group_files_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_files_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE workspace_members ADD CONSTRAINT fk_workspace_members_workspace_id FOREIGN KEY (workspace_id) REFERENCES workspaces(workspace_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_workspace_members_workspace_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE workspace_members ADD CONSTRAINT fk_workspace_members_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_workspace_members_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE files ADD CONSTRAINT fk_files_workspace_id FOREIGN KEY (workspace_id) REFERENCES workspaces(workspace_id) ON DELETE SET NULL").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_files_workspace_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE access_requests ADD CONSTRAINT fk_access_requests_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_access_requests_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE access_requests ADD CONSTRAINT fk_access_requests_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_access_requests_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE notifications ADD CONSTRAINT fk_notifications_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_notifications_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE comment_threads ADD CONSTRAINT fk_comment_threads_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comment_threads_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE comment_threads ADD CONSTRAINT fk_comment_threads_created_by FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comment_threads_created_by already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE comments ADD CONSTRAINT fk_comments_thread_id FOREIGN KEY (thread_id) REFERENCES comment_threads(thread_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comments_thread_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE comments ADD CONSTRAINT fk_comments_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comments_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE suggestions ADD CONSTRAINT fk_suggestions_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_suggestions_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = db.Exec("ALTER TABLE suggestions ADD CONSTRAINT fk_suggestions_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_suggestions_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = backfillSortKeys(db)
+	if err != nil {
+		log.Fatalf("error while computing the sort keys of the characters: %v", err)
+	}
+
 	fmt.Println("Migration successful")
 
 	return db
 }
+
+// backfillSortKeys computes the sort key of the characters stored before the column existed.
+func backfillSortKeys(db *gorm.DB) error {
+	var chars []models.FilesContents
+	return db.Select("content_id", "char_path").
+		Where("sort_key IS NULL").
+		FindInBatches(&chars, 1000, func(tx *gorm.DB, batch int) error {
+			for _, char := range chars {
+				err := db.Model(&models.FilesContents{}).
+					Where("content_id = ?", char.ContentsID).
+					Update("sort_key", lseqUtils.SortKey(lseqUtils.Decode(char.Path))).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
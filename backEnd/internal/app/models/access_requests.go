@@ -0,0 +1,41 @@
+package models
+
+const TableNameAccessRequest = "access_requests"
+
+// Status of an access request
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestDenied   = "denied"
+)
+
+// AccessRequest mapped from table <access_requests>
+// A user has at most one pending request per file, Role is the role granted on approval.
+type AccessRequestMigration struct {
+	RequestID  string `gorm:"column:request_id;type:uuid;default:gen_random_uuid();primaryKey" json:"request_id"`
+	FileUUID   string `gorm:"column:file_uuid;not null;index;uniqueIndex:idx_access_requests_pending,where:status = 'pending'" json:"file_uuid"`
+	UserID     uint32 `gorm:"column:user_id;not null;uniqueIndex:idx_access_requests_pending,where:status = 'pending'" json:"user_id"`
+	Message    string `gorm:"column:message;not null;default:'';size:500" json:"message"`
+	Status     string `gorm:"column:status;not null;default:pending" json:"status"`
+	Role       string `gorm:"column:role;not null;default:''" json:"role"`
+	CreatedAt  int64  `gorm:"column:created_at;not null" json:"created_at"`
+	ResolvedAt int64  `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+}
+
+// TableName AccessRequest's table name
+func (*AccessRequestMigration) TableName() string {
+	return TableNameAccessRequest
+}
+
+type AccessRequest struct {
+	RequestID  string `gorm:"column:request_id;type:uuid;default:gen_random_uuid();primaryKey" json:"request_id"`
+	FileUUID   string `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	UserID     uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	Message    string `gorm:"column:message;not null;default:''" json:"message"`
+	Status     string `gorm:"column:status;not null;default:pending" json:"status"`
+	Role       string `gorm:"column:role;not null;default:''" json:"role"`
+	CreatedAt  int64  `gorm:"column:created_at;not null" json:"created_at"`
+	ResolvedAt int64  `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+	File       File   `gorm:"foreignKey:FileUUID" json:"-"`
+	User       User   `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,37 @@
+package models
+
+const TableNameCommentThread = "comment_threads"
+
+// CommentThread mapped from table <comment_threads>
+// The thread is anchored to the CRDT paths of the first and last characters of the passage,
+// the paths keep their order even once the characters are deleted or garbage-collected.
+type CommentThreadMigration struct {
+	ThreadID    string `gorm:"column:thread_id;type:uuid;default:gen_random_uuid();primaryKey" json:"thread_id"`
+	FileUUID    string `gorm:"column:file_uuid;not null;index" json:"file_uuid"`
+	AnchorStart []byte `gorm:"column:anchor_start;not null" json:"anchor_start"`
+	AnchorEnd   []byte `gorm:"column:anchor_end;not null" json:"anchor_end"`
+	Resolved    bool   `gorm:"column:resolved;not null;default:false" json:"resolved"`
+	ResolvedBy  uint32 `gorm:"column:resolved_by;not null;default:0" json:"resolved_by"`
+	ResolvedAt  int64  `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+	CreatedBy   uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt   int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName CommentThread's table name
+func (*CommentThreadMigration) TableName() string {
+	return TableNameCommentThread
+}
+
+type CommentThread struct {
+	ThreadID    string    `gorm:"column:thread_id;type:uuid;default:gen_random_uuid();primaryKey" json:"thread_id"`
+	FileUUID    string    `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	AnchorStart []byte    `gorm:"column:anchor_start;not null" json:"anchor_start"`
+	AnchorEnd   []byte    `gorm:"column:anchor_end;not null" json:"anchor_end"`
+	Resolved    bool      `gorm:"column:resolved;not null;default:false" json:"resolved"`
+	ResolvedBy  uint32    `gorm:"column:resolved_by;not null;default:0" json:"resolved_by"`
+	ResolvedAt  int64     `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+	CreatedBy   uint32    `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt   int64     `gorm:"column:created_at;not null" json:"created_at"`
+	Comments    []Comment `gorm:"foreignKey:ThreadID" json:"-"`
+	File        File      `gorm:"foreignKey:FileUUID" json:"-"`
+}
@@ -0,0 +1,30 @@
+package models
+
+const TableNameComment = "comments"
+
+// Comment mapped from table <comments>
+// The root comment opens the thread, the other ones are its replies.
+type CommentMigration struct {
+	CommentID string `gorm:"column:comment_id;type:uuid;default:gen_random_uuid();primaryKey" json:"comment_id"`
+	ThreadID  string `gorm:"column:thread_id;type:uuid;not null;index" json:"thread_id"`
+	UserID    uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	Body      string `gorm:"column:body;type:text;not null" json:"body"`
+	Root      bool   `gorm:"column:root;not null;default:false" json:"root"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName Comment's table name
+func (*CommentMigration) TableName() string {
+	return TableNameComment
+}
+
+type Comment struct {
+	CommentID     string        `gorm:"column:comment_id;type:uuid;default:gen_random_uuid();primaryKey" json:"comment_id"`
+	ThreadID      string        `gorm:"column:thread_id;type:uuid;not null" json:"thread_id"`
+	UserID        uint32        `gorm:"column:user_id;not null" json:"user_id"`
+	Body          string        `gorm:"column:body;type:text;not null" json:"body"`
+	Root          bool          `gorm:"column:root;not null;default:false" json:"root"`
+	CreatedAt     int64         `gorm:"column:created_at;not null" json:"created_at"`
+	CommentThread CommentThread `gorm:"foreignKey:ThreadID" json:"-"`
+	User          User          `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,38 @@
+package models
+
+const TableNameDocumentOperation = "document_operations"
+
+// DocumentOperation mapped from table <document_operations>
+type DocumentOperationMigration struct {
+	OperationID   uint64 `gorm:"column:operation_id;primaryKey" json:"operation_id"`
+	FileUUID      string `gorm:"column:file_uuid;not null;uniqueIndex:idx_document_operations_file_revision" json:"file_uuid"`
+	Revision      int64  `gorm:"column:revision;not null;uniqueIndex:idx_document_operations_file_revision" json:"revision"`
+	OperationType string `gorm:"column:operation_type;not null" json:"operation_type"`
+	UserID        uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	SessionID     string `gorm:"column:session_id;not null" json:"session_id"`
+	Payload       string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Kind          string `gorm:"column:kind;not null;default:edit" json:"kind"`
+	UndoOf        int64  `gorm:"column:undo_of;not null;default:0" json:"undo_of"`
+	Undone        bool   `gorm:"column:undone;not null;default:false" json:"undone"`
+	CreatedAt     int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName DocumentOperation's table name
+func (*DocumentOperationMigration) TableName() string {
+	return TableNameDocumentOperation
+}
+
+type DocumentOperation struct {
+	OperationID   uint64 `gorm:"column:operation_id;primaryKey" json:"operation_id"`
+	FileUUID      string `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	Revision      int64  `gorm:"column:revision;not null" json:"revision"`
+	OperationType string `gorm:"column:operation_type;not null" json:"operation_type"`
+	UserID        uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	SessionID     string `gorm:"column:session_id;not null" json:"session_id"`
+	Payload       string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Kind          string `gorm:"column:kind;not null;default:edit" json:"kind"`
+	UndoOf        int64  `gorm:"column:undo_of;not null;default:0" json:"undo_of"`
+	Undone        bool   `gorm:"column:undone;not null;default:false" json:"undone"`
+	CreatedAt     int64  `gorm:"column:created_at;not null" json:"created_at"`
+	File          File   `gorm:"foreignKey:FileUUID"`
+}
@@ -0,0 +1,22 @@
+package models
+
+const TableNameDocumentSnapshot = "document_snapshots"
+
+// DocumentSnapshot mapped from table <document_snapshots>
+type DocumentSnapshotMigration struct {
+	FileUUID  string `gorm:"column:file_uuid;primaryKey" json:"file_uuid"`
+	Revision  int64  `gorm:"column:revision;not null" json:"revision"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName DocumentSnapshot's table name
+func (*DocumentSnapshotMigration) TableName() string {
+	return TableNameDocumentSnapshot
+}
+
+type DocumentSnapshot struct {
+	FileUUID  string `gorm:"column:file_uuid;primaryKey" json:"file_uuid"`
+	Revision  int64  `gorm:"column:revision;not null" json:"revision"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+	File      File   `gorm:"foreignKey:FileUUID"`
+}
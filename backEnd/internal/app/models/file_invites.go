@@ -0,0 +1,35 @@
+package models
+
+const TableNameFileInvite = "file_invites"
+
+// FileInvite mapped from table <file_invites>
+// ExpiresAt and MaxUses are 0 when the link has no limit.
+type FileInviteMigration struct {
+	InviteID  string `gorm:"column:invite_id;type:uuid;default:gen_random_uuid();primaryKey" json:"invite_id"`
+	FileUUID  string `gorm:"column:file_uuid;not null;index" json:"file_uuid"`
+	Token     string `gorm:"column:token;not null;size:64;uniqueIndex" json:"token"`
+	Role      string `gorm:"column:role;not null;default:Viewer" json:"role"`
+	CreatedBy uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+	ExpiresAt int64  `gorm:"column:expires_at;not null;default:0" json:"expires_at"`
+	MaxUses   int    `gorm:"column:max_uses;not null;default:0" json:"max_uses"`
+	Uses      int    `gorm:"column:uses;not null;default:0" json:"uses"`
+}
+
+// TableName FileInvite's table name
+func (*FileInviteMigration) TableName() string {
+	return TableNameFileInvite
+}
+
+type FileInvite struct {
+	InviteID  string `gorm:"column:invite_id;type:uuid;default:gen_random_uuid();primaryKey" json:"invite_id"`
+	FileUUID  string `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	Token     string `gorm:"column:token;not null" json:"token"`
+	Role      string `gorm:"column:role;not null;default:Viewer" json:"role"`
+	CreatedBy uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+	ExpiresAt int64  `gorm:"column:expires_at;not null;default:0" json:"expires_at"`
+	MaxUses   int    `gorm:"column:max_uses;not null;default:0" json:"max_uses"`
+	Uses      int    `gorm:"column:uses;not null;default:0" json:"uses"`
+	File      File   `gorm:"foreignKey:FileUUID" json:"-"`
+}
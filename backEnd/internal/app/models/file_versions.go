@@ -0,0 +1,30 @@
+package models
+
+const TableNameFileVersion = "file_versions"
+
+// FileVersion mapped from table <file_versions>
+type FileVersionMigration struct {
+	VersionID string `gorm:"column:version_id;type:uuid;default:gen_random_uuid();primaryKey" json:"version_id"`
+	FileUUID  string `gorm:"column:file_uuid;not null;index" json:"file_uuid"`
+	Name      string `gorm:"column:name;not null;size:100" json:"name"`
+	Revision  int64  `gorm:"column:revision;not null" json:"revision"`
+	CreatedBy uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+	Content   string `gorm:"column:content;type:jsonb;not null" json:"-"`
+}
+
+// TableName FileVersion's table name
+func (*FileVersionMigration) TableName() string {
+	return TableNameFileVersion
+}
+
+type FileVersion struct {
+	VersionID string `gorm:"column:version_id;type:uuid;default:gen_random_uuid();primaryKey" json:"version_id"`
+	FileUUID  string `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	Name      string `gorm:"column:name;not null" json:"name"`
+	Revision  int64  `gorm:"column:revision;not null" json:"revision"`
+	CreatedBy uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+	Content   string `gorm:"column:content;type:jsonb;not null" json:"-"`
+	File      File   `gorm:"foreignKey:FileUUID" json:"-"`
+}
@@ -7,6 +7,9 @@ type FileMigration struct {
 	FileUUID      string `gorm:"column:file_uuid;primaryKey" json:"file_uuid"`
 	FileName      string `gorm:"column:file_name" json:"file_name"`
 	FileUpdatedAt int64  `gorm:"column:file_updated_at" json:"file_updated_at"`
+	FileRevision  int64  `gorm:"column:file_revision;not null;default:0" json:"file_revision"`
+	// WorkspaceID is nil for a personal file
+	WorkspaceID *string `gorm:"column:workspace_id;type:uuid;index" json:"workspace_id"`
 }
 
 // TableName File's table name
@@ -18,5 +21,7 @@ type File struct {
 	FileUUID      string          `gorm:"column:file_uuid;primaryKey" json:"file_uuid"`
 	FileName      string          `gorm:"column:file_name" json:"file_name"`
 	FileUpdatedAt int64           `gorm:"column:file_updated_at" json:"file_updated_at"`
+	FileRevision  int64           `gorm:"column:file_revision;not null;default:0" json:"file_revision"`
+	WorkspaceID   *string         `gorm:"column:workspace_id;type:uuid" json:"workspace_id"`
 	FilesContents []FilesContents `gorm:"foreignKey:FileUUID"`
 }
@@ -1,15 +1,76 @@
 package models
 
+import "errors"
+
 const TableNameFileContents = "files_contents"
 
+// Style bits of a character
+const (
+	StyleBold uint32 = 1 << iota
+	StyleItalic
+	StyleUnderline
+	StyleStrike
+	StyleCode
+)
+
+// Block types, stored on the newline character that ends the block
+const (
+	BlockParagraph = "paragraph"
+	BlockHeading   = "heading"
+	BlockBullet    = "bullet"
+	BlockNumbered  = "numbered"
+	BlockQuote     = "quote"
+	BlockCode      = "code"
+)
+
+const (
+	MaxHeadingLevel = 6
+	MaxListDepth    = 8
+)
+
+// ValidateBlock checks the level matches the block type: 1 to 6 for a heading,
+// the nesting depth for a list and 0 otherwise. Only code blocks have a language.
+func ValidateBlock(block string, level int, language string) error {
+	if language != "" && block != BlockCode {
+		return errors.New("only code blocks have a language")
+	}
+	switch block {
+	case BlockHeading:
+		if level < 1 || level > MaxHeadingLevel {
+			return errors.New("heading level must be between 1 and 6")
+		}
+		return nil
+	case BlockBullet, BlockNumbered:
+		if level < 0 || level > MaxListDepth {
+			return errors.New("list depth must be between 0 and 8")
+		}
+		return nil
+	case BlockParagraph, BlockQuote, BlockCode:
+		if level != 0 {
+			return errors.New("block has no level")
+		}
+		return nil
+	default:
+		return errors.New("unknown block type")
+	}
+}
+
 // File mapped from table <files_contents>
 type FilesContentsMigration struct {
 	ContentsID     string `gorm:"column:content_id;type:uuid;default:gen_random_uuid();primaryKey" json:"content_id"`
 	CharacterValue []byte `gorm:"column:char_value;not null" json:"char_value"`
-	Path           []byte `gorm:"column:char_path;not null" json:"path"`
+	Path           []byte `gorm:"column:char_path;not null;uniqueIndex:idx_files_contents_file_path" json:"path"`
+	SortKey        []byte `gorm:"column:sort_key;index:idx_files_contents_file_sort_key,priority:2" json:"-"`
 	Style          uint32 `gorm:"column:char_style;not null" json:"style"`
 	Color          string `gorm:"column:color;not null" json:"color"`
-	FileUUID       string `gorm:"column:file_uuid;not null"`
+	Deleted        bool   `gorm:"column:deleted;not null;default:false" json:"deleted"`
+	InsertedRev    int64  `gorm:"column:inserted_revision;not null;default:0" json:"inserted_revision"`
+	DeletedRev     int64  `gorm:"column:deleted_revision;not null;default:0" json:"deleted_revision"`
+	StyleClock     string `gorm:"column:style_clock;type:jsonb;not null;default:'{}'" json:"style_clock"`
+	Block          string `gorm:"column:block_type;not null;default:paragraph" json:"block"`
+	BlockLevel     int    `gorm:"column:block_level;not null;default:0" json:"block_level"`
+	BlockLanguage  string `gorm:"column:block_language;not null;default:''" json:"block_language"`
+	FileUUID       string `gorm:"column:file_uuid;not null;uniqueIndex:idx_files_contents_file_path;index:idx_files_contents_file_sort_key,priority:1"`
 }
 
 // TableName File's table name
@@ -20,9 +81,17 @@ func (*FilesContentsMigration) TableName() string {
 type FilesContents struct {
 	ContentsID     string `gorm:"column:content_id;type:uuid;default:gen_random_uuid();primaryKey" json:"content_id"`
 	CharacterValue []byte `gorm:"column:char_value;not null" json:"char_value"`
-	Path           []byte `gorm:"column:path;not null" json:"path"`
-	Style          uint32 `gorm:"column:style;not null" json:"style"`
+	Path           []byte `gorm:"column:char_path;not null" json:"path"`
+	SortKey        []byte `gorm:"column:sort_key" json:"-"`
+	Style          uint32 `gorm:"column:char_style;not null" json:"style"`
 	Color          string `gorm:"column:color;not null" json:"color"`
+	Deleted        bool   `gorm:"column:deleted;not null;default:false" json:"deleted"`
+	InsertedRev    int64  `gorm:"column:inserted_revision;not null;default:0" json:"inserted_revision"`
+	DeletedRev     int64  `gorm:"column:deleted_revision;not null;default:0" json:"deleted_revision"`
+	StyleClock     string `gorm:"column:style_clock;type:jsonb;not null;default:'{}'" json:"style_clock"`
+	Block          string `gorm:"column:block_type;not null;default:paragraph" json:"block"`
+	BlockLevel     int    `gorm:"column:block_level;not null;default:0" json:"block_level"`
+	BlockLanguage  string `gorm:"column:block_language;not null;default:''" json:"block_language"`
 	FileUUID       string `gorm:"column:file_uuid;not null"`
 	File           File   `gorm:"foreignKey:FileUUID"`
 }
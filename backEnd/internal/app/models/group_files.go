@@ -0,0 +1,24 @@
+package models
+
+const TableNameGroupFile = "group_files"
+
+// GroupFile mapped from table <group_files>
+// Every member of the group gets Role on the file.
+type GroupFileMigration struct {
+	GroupID  string `gorm:"column:group_id;type:uuid;primaryKey;not null" json:"group_id"`
+	FileUUID string `gorm:"column:file_uuid;primaryKey;not null;index" json:"file_uuid"`
+	Role     string `gorm:"column:role;not null;default:Collaborator" json:"role"`
+}
+
+// TableName GroupFile's table name
+func (*GroupFileMigration) TableName() string {
+	return TableNameGroupFile
+}
+
+type GroupFile struct {
+	GroupID  string `gorm:"column:group_id;type:uuid;primaryKey;not null" json:"group_id"`
+	FileUUID string `gorm:"column:file_uuid;primaryKey;not null" json:"file_uuid"`
+	Role     string `gorm:"column:role;not null;default:Collaborator" json:"role"`
+	Group    Group  `gorm:"foreignKey:GroupID" json:"-"`
+	File     File   `gorm:"foreignKey:FileUUID" json:"-"`
+}
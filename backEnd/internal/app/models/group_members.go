@@ -0,0 +1,39 @@
+package models
+
+import "errors"
+
+const TableNameGroupMember = "group_members"
+
+const (
+	GroupRoleAdmin  = "Admin"
+	GroupRoleMember = "Member"
+)
+
+// GroupMember mapped from table <group_members>
+type GroupMemberMigration struct {
+	GroupID string `gorm:"column:group_id;type:uuid;primaryKey;not null" json:"group_id"`
+	UserID  uint32 `gorm:"column:user_id;primaryKey;not null;index" json:"user_id"`
+	Role    string `gorm:"column:role;not null;default:Member" json:"role"`
+}
+
+// TableName GroupMember's table name
+func (*GroupMemberMigration) TableName() string {
+	return TableNameGroupMember
+}
+
+func (gm *GroupMemberMigration) Validate() error {
+	switch gm.Role {
+	case GroupRoleAdmin, GroupRoleMember:
+		return nil
+	default:
+		return errors.New("role must be Admin or Member")
+	}
+}
+
+type GroupMember struct {
+	GroupID string `gorm:"column:group_id;type:uuid;primaryKey;not null" json:"group_id"`
+	UserID  uint32 `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
+	Role    string `gorm:"column:role;not null;default:Member" json:"role"`
+	Group   Group  `gorm:"foreignKey:GroupID" json:"-"`
+	User    User   `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+const TableNameGroup = "groups"
+
+// Group mapped from table <groups>
+type GroupMigration struct {
+	GroupID   string `gorm:"column:group_id;type:uuid;default:gen_random_uuid();primaryKey" json:"group_id"`
+	Name      string `gorm:"column:name;not null;size:50" json:"name"`
+	CreatedBy uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName Group's table name
+func (*GroupMigration) TableName() string {
+	return TableNameGroup
+}
+
+type Group struct {
+	GroupID      string        `gorm:"column:group_id;type:uuid;default:gen_random_uuid();primaryKey" json:"group_id"`
+	Name         string        `gorm:"column:name;not null" json:"name"`
+	CreatedBy    uint32        `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt    int64         `gorm:"column:created_at;not null" json:"created_at"`
+	GroupMembers []GroupMember `gorm:"foreignKey:GroupID" json:"-"`
+	GroupFiles   []GroupFile   `gorm:"foreignKey:GroupID" json:"-"`
+}
@@ -0,0 +1,29 @@
+package models
+
+const TableNameNotification = "notifications"
+
+// Notification mapped from table <notifications>
+// Payload is the JSON of the data of the notification, as sent on the websocket.
+type NotificationMigration struct {
+	NotificationID   string `gorm:"column:notification_id;type:uuid;default:gen_random_uuid();primaryKey" json:"notification_id"`
+	UserID           uint32 `gorm:"column:user_id;not null;index:idx_notifications_user_read" json:"user_id"`
+	NotificationType string `gorm:"column:notification_type;not null" json:"notification_type"`
+	Payload          string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Read             bool   `gorm:"column:read;not null;default:false;index:idx_notifications_user_read" json:"read"`
+	CreatedAt        int64  `gorm:"column:created_at;not null;index" json:"created_at"`
+}
+
+// TableName Notification's table name
+func (*NotificationMigration) TableName() string {
+	return TableNameNotification
+}
+
+type Notification struct {
+	NotificationID   string `gorm:"column:notification_id;type:uuid;default:gen_random_uuid();primaryKey" json:"notification_id"`
+	UserID           uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	NotificationType string `gorm:"column:notification_type;not null" json:"notification_type"`
+	Payload          string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	Read             bool   `gorm:"column:read;not null;default:false" json:"read"`
+	CreatedAt        int64  `gorm:"column:created_at;not null" json:"created_at"`
+	User             User   `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,48 @@
+package models
+
+const TableNameSuggestion = "suggestions"
+
+// Status of a suggestion
+const (
+	SuggestionPending  = "pending"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion mapped from table <suggestions>
+// A suggested insert or delete of one character, applied to files_contents only once accepted.
+// Payload is the suggested character, a character carries at most one pending suggestion.
+type SuggestionMigration struct {
+	SuggestionID string `gorm:"column:suggestion_id;type:uuid;default:gen_random_uuid();primaryKey" json:"suggestion_id"`
+	FileUUID     string `gorm:"column:file_uuid;not null;index;uniqueIndex:idx_suggestions_pending_path,where:status = 'pending'" json:"file_uuid"`
+	Path         []byte `gorm:"column:char_path;not null;uniqueIndex:idx_suggestions_pending_path,where:status = 'pending'" json:"path"`
+	Kind         string `gorm:"column:kind;not null" json:"kind"`
+	Payload      string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	UserID       uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	SessionID    string `gorm:"column:session_id;not null" json:"session_id"`
+	Status       string `gorm:"column:status;not null;default:pending" json:"status"`
+	ResolvedBy   uint32 `gorm:"column:resolved_by;not null;default:0" json:"resolved_by"`
+	ResolvedAt   int64  `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+	CreatedAt    int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName Suggestion's table name
+func (*SuggestionMigration) TableName() string {
+	return TableNameSuggestion
+}
+
+type Suggestion struct {
+	SuggestionID string `gorm:"column:suggestion_id;type:uuid;default:gen_random_uuid();primaryKey" json:"suggestion_id"`
+	FileUUID     string `gorm:"column:file_uuid;not null" json:"file_uuid"`
+	Path         []byte `gorm:"column:char_path;not null" json:"path"`
+	Kind         string `gorm:"column:kind;not null" json:"kind"`
+	Payload      string `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	UserID       uint32 `gorm:"column:user_id;not null" json:"user_id"`
+	SessionID    string `gorm:"column:session_id;not null" json:"session_id"`
+	Status       string `gorm:"column:status;not null;default:pending" json:"status"`
+	ResolvedBy   uint32 `gorm:"column:resolved_by;not null;default:0" json:"resolved_by"`
+	ResolvedAt   int64  `gorm:"column:resolved_at;not null;default:0" json:"resolved_at"`
+	CreatedAt    int64  `gorm:"column:created_at;not null" json:"created_at"`
+	File         File   `gorm:"foreignKey:FileUUID" json:"-"`
+	User         User   `gorm:"foreignKey:UserID" json:"-"`
+}
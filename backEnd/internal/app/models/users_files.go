@@ -7,13 +7,17 @@ const TableNameUsersFile = "users_files"
 const (
 	RoleOwner        = "Owner"
 	RoleCollaborator = "Collaborator"
+	RoleCommenter    = "Commenter"
+	RoleViewer       = "Viewer"
 )
 
 // UsersFile mapped from table <users_files>
+// ExpiresAt is 0 for a permanent access.
 type UsersFileMigration struct {
-	UserID   uint32 `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
-	FileUUID string `gorm:"column:file_uuid;primaryKey;not null" json:"file_uuid"`
-	Role     string `gorm:"column:role;default:Collaborator" json:"role"`
+	UserID    uint32 `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
+	FileUUID  string `gorm:"column:file_uuid;primaryKey;not null" json:"file_uuid"`
+	Role      string `gorm:"column:role;default:Collaborator" json:"role"`
+	ExpiresAt int64  `gorm:"column:expires_at;not null;default:0;index" json:"expires_at"`
 }
 
 // TableName UsersFile's table name
@@ -23,17 +27,18 @@ func (*UsersFileMigration) TableName() string {
 
 func (uf *UsersFileMigration) Validate() error {
 	switch uf.Role {
-	case RoleOwner, RoleCollaborator:
+	case RoleOwner, RoleCollaborator, RoleCommenter, RoleViewer:
 		return nil
 	default:
-		return errors.New("role must be Owner or Collaborator")
+		return errors.New("role must be Owner, Collaborator, Commenter or Viewer")
 	}
 }
 
 type UsersFile struct {
-	UserID   uint32 `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
-	FileUUID string `gorm:"column:file_uuid;primaryKey;not null" json:"file_uuid"`
-	Role     string `gorm:"column:role;default:Collaborator" json:"role"`
-	File     File   `gorm:"foreignKey:FileUUID"`
-	User     User   `gorm:"foreignKey:UserID"`
+	UserID    uint32 `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
+	FileUUID  string `gorm:"column:file_uuid;primaryKey;not null" json:"file_uuid"`
+	Role      string `gorm:"column:role;default:Collaborator" json:"role"`
+	ExpiresAt int64  `gorm:"column:expires_at;not null;default:0" json:"expires_at"`
+	File      File   `gorm:"foreignKey:FileUUID"`
+	User      User   `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import "errors"
+
+const TableNameWorkspaceMember = "workspace_members"
+
+const (
+	WorkspaceRoleAdmin  = "Admin"
+	WorkspaceRoleMember = "Member"
+)
+
+// WorkspaceMember mapped from table <workspace_members>
+type WorkspaceMemberMigration struct {
+	WorkspaceID string `gorm:"column:workspace_id;type:uuid;primaryKey;not null" json:"workspace_id"`
+	UserID      uint32 `gorm:"column:user_id;primaryKey;not null;index" json:"user_id"`
+	Role        string `gorm:"column:role;not null;default:Member" json:"role"`
+}
+
+// TableName WorkspaceMember's table name
+func (*WorkspaceMemberMigration) TableName() string {
+	return TableNameWorkspaceMember
+}
+
+func (wm *WorkspaceMemberMigration) Validate() error {
+	switch wm.Role {
+	case WorkspaceRoleAdmin, WorkspaceRoleMember:
+		return nil
+	default:
+		return errors.New("role must be Admin or Member")
+	}
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string    `gorm:"column:workspace_id;type:uuid;primaryKey;not null" json:"workspace_id"`
+	UserID      uint32    `gorm:"column:user_id;primaryKey;not null" json:"user_id"`
+	Role        string    `gorm:"column:role;not null;default:Member" json:"role"`
+	Workspace   Workspace `gorm:"foreignKey:WorkspaceID" json:"-"`
+	User        User      `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,28 @@
+package models
+
+const TableNameWorkspace = "workspaces"
+
+// Workspace mapped from table <workspaces>
+// Every member of the workspace gets DefaultRole on its files.
+type WorkspaceMigration struct {
+	WorkspaceID string `gorm:"column:workspace_id;type:uuid;default:gen_random_uuid();primaryKey" json:"workspace_id"`
+	Name        string `gorm:"column:name;not null;size:50" json:"name"`
+	DefaultRole string `gorm:"column:default_role;not null;default:Collaborator" json:"default_role"`
+	CreatedBy   uint32 `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt   int64  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+// TableName Workspace's table name
+func (*WorkspaceMigration) TableName() string {
+	return TableNameWorkspace
+}
+
+type Workspace struct {
+	WorkspaceID      string            `gorm:"column:workspace_id;type:uuid;default:gen_random_uuid();primaryKey" json:"workspace_id"`
+	Name             string            `gorm:"column:name;not null" json:"name"`
+	DefaultRole      string            `gorm:"column:default_role;not null;default:Collaborator" json:"default_role"`
+	CreatedBy        uint32            `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt        int64             `gorm:"column:created_at;not null" json:"created_at"`
+	WorkspaceMembers []WorkspaceMember `gorm:"foreignKey:WorkspaceID" json:"-"`
+	Files            []File            `gorm:"foreignKey:WorkspaceID" json:"-"`
+}
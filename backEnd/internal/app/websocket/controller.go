@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
@@ -15,11 +16,27 @@ import (
 )
 
 const (
-	MessageTypeAuthSuccess = "Auth_success"
-	MessageTypeAuthFailed  = "Auth_failed"
-	MessageTypePingRequest = "Ping"
+	MessageTypeAuthSuccess     = "Auth_success"
+	MessageTypeAuthFailed      = "Auth_failed"
+	MessageTypePingRequest     = "Ping"
+	MessageTypeOpFailed        = "Operation_failed"
+	MessageTypeOpAck           = "Operation_ack"
+	MessageTypeJoinFailed      = "Join_failed"
+	MessageTypeDocumentContent = "Document_content"
+	MessageTypeOperations      = "Operations"
+	MessageTypePresenceList    = "Presence_list"
+	MessageTypeNotification    = "notification"
+	MessageTypeSuggestMode     = "Suggest_mode"
 )
 
+// maxUnreadOnAuth caps the unread notifications sent on authentication, the older ones
+// are listed through the notification endpoints.
+const maxUnreadOnAuth = 50
+
+// sendBufferSize bounds the messages waiting for the connection, past it they are dropped.
+// It holds the unread notifications sent at once on authentication.
+const sendBufferSize = 2 * maxUnreadOnAuth
+
 type Socket struct {
 	conn *websocket.Conn
 	ctx  *gin.Context
@@ -57,6 +74,9 @@ type ConnectionManager struct {
 	writeTimeout    time.Duration
 	readTimeout     time.Duration
 	currentFileUUID string
+	cursor          cursorThrottle
+	role            fileRole
+	suggesting      atomic.Bool
 }
 
 type SafeConnectionPool struct {
@@ -94,7 +114,7 @@ func WebSocketHandler(c *gin.Context, db *gorm.DB, ctx context.Context) {
 
 	var manager = ConnectionManager{
 		clientSocket: clientSocket,
-		send:         make(chan []byte),
+		send:         make(chan []byte, sendBufferSize),
 		connections:  sConnectionPool,
 		pingInterval: time.Second * 30,
 		readTimeout:  time.Second * 60,
@@ -114,6 +134,7 @@ func (manager *ConnectionManager) Start(db *gorm.DB, srvContext context.Context)
 	<-manager.ctx.Done()
 	fmt.Println("🔴 websocket disconnected")
 	Cleanupctx := context.Background()
+	manager.leaveFile(Cleanupctx)
 	manager.DeleteLocal()
 	redisUtils.DeleteSessionInRedis(manager.clientSocket.client.SessionID, Cleanupctx)
 	var docSessionsList []string
@@ -50,6 +50,7 @@ func TestMain(m *testing.M) {
 	}
 
 	redisUtils.CreateRedis(context.Background())
+	websocket.Init()
 	setupTestRS256KeyPair()
 
 	ts = httptest.NewServer(createTestRoute())
@@ -193,6 +194,188 @@ func TestWebSocketAuth(t *testing.T) {
 	require.Equal(t, websocket.MessageTypeAuthFailed, responseObj.Type)
 }
 
+func TestWebSocketAuthClaimedUser(t *testing.T) {
+	testenv.CleanTables()
+
+	ownerID := insertSessionUser(t, "owner")
+	intruderID := insertSessionUser(t, "intruder")
+
+	token, err := jwtUtils.CreateJWT(t.Context(), "intruder", testenv.DB)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Add("User-Agent", testUserAgent)
+	ws, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	// CASE a valid token claiming the ID of another user
+	send(t, ws, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"owner","UserID":%d,"SessionID":"intruder-session"}}`, token, ownerID))
+	readType(t, ws, websocket.MessageTypeAuthFailed)
+
+	// CASE the same token claiming its own ID
+	send(t, ws, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"intruder","UserID":%d,"SessionID":"intruder-session"}}`, token, intruderID))
+	readType(t, ws, websocket.MessageTypeAuthSuccess)
+}
+
+func TestJoinFileClaimedUser(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	require.NoError(t, gorm.G[models.File](db).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: "private"}))
+	memberID := insertSessionUser(t, "member")
+	require.NoError(t, gorm.G[models.UsersFile](db).Create(t.Context(), &models.UsersFile{UserID: memberID, FileUUID: fileUUID, Role: models.RoleOwner}))
+	intruderID := insertSessionUser(t, "intruder")
+
+	token, err := jwtUtils.CreateJWT(t.Context(), "intruder", db)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Add("User-Agent", testUserAgent)
+	intruder, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	require.NoError(t, err)
+	defer intruder.Close()
+
+	// CASE a non-member claiming the member's ID can't join
+	send(t, intruder, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"member","UserID":%d,"SessionID":"intruder-session"}}`, token, memberID))
+	readType(t, intruder, websocket.MessageTypeAuthFailed)
+	send(t, intruder, fmt.Sprintf(`{"type":"joinFile","data":"%s"}`, fileUUID))
+	readType(t, intruder, websocket.MessageTypeJoinFailed)
+
+	// CASE authenticated as themselves, they still can't
+	send(t, intruder, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"intruder","UserID":%d,"SessionID":"intruder-session"}}`, token, intruderID))
+	readType(t, intruder, websocket.MessageTypeAuthSuccess)
+	send(t, intruder, fmt.Sprintf(`{"type":"joinFile","data":"%s"}`, fileUUID))
+	readType(t, intruder, websocket.MessageTypeJoinFailed)
+
+	// CASE the member joins
+	member := authenticate(t, "member", memberID)
+	defer member.Close()
+	send(t, member, fmt.Sprintf(`{"type":"joinFile","data":"%s"}`, fileUUID))
+	readType(t, member, websocket.MessageTypeDocumentContent)
+}
+
+const testUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"
+
+// insertSessionUser creates the user with a session of the test user agent.
+func insertSessionUser(t *testing.T, username string) uint32 {
+	db := testenv.DB
+	err := db.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](db).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+
+	now := time.Now().Unix()
+	err = db.Exec("INSERT INTO sessions (user_id, created_at, expires_at, agent) VALUES (?, ?, ?, ?)", user.UserID, now-300, now+300, testUserAgent).Error
+	require.NoError(t, err)
+	return user.UserID
+}
+
+// authenticate opens a connection and authenticates it as the user.
+func authenticate(t *testing.T, username string, userID uint32) *gorillaws.Conn {
+	token, err := jwtUtils.CreateJWT(t.Context(), username, testenv.DB)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Add("User-Agent", testUserAgent)
+	ws, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	require.NoError(t, err)
+
+	send(t, ws, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"%s","UserID":%d,"SessionID":"%s-session"}}`, token, username, userID, username))
+	readType(t, ws, websocket.MessageTypeAuthSuccess)
+	return ws
+}
+
+func send(t *testing.T, ws *gorillaws.Conn, msg string) {
+	require.NoError(t, ws.WriteMessage(gorillaws.TextMessage, []byte(msg)))
+}
+
+// readType reads the messages of the connection until one of the given type,
+// each message being handled concurrently the others are skipped.
+func readType(t *testing.T, ws *gorillaws.Conn, messageType string) []byte {
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		_, resp, err := ws.ReadMessage()
+		require.NoError(t, err)
+
+		var response struct {
+			Type string `json:"type"`
+		}
+		require.NoError(t, json.Unmarshal(resp, &response))
+		if response.Type == messageType {
+			return resp
+		}
+	}
+}
+
+func TestUnreadNotificationsOnAuth(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+
+	userID := insertSessionUser(t, "reader")
+	for i := range 60 {
+		stored := models.Notification{
+			UserID:           userID,
+			NotificationType: "file_shared",
+			Payload:          fmt.Sprintf(`{"index": %d}`, i),
+			Read:             i%10 == 9,
+			CreatedAt:        int64(i + 1),
+		}
+		require.NoError(t, gorm.G[models.Notification](db).Create(t.Context(), &stored))
+	}
+
+	// CASE the 50 most recent unread notifications, oldest first, none dropped
+	ws := authenticate(t, "reader", userID)
+	defer ws.Close()
+
+	var indexes []int
+	for len(indexes) < 50 {
+		var response struct {
+			Data struct {
+				Read     bool `json:"read"`
+				FileData struct {
+					Index int `json:"index"`
+				} `json:"fileData"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(readType(t, ws, websocket.MessageTypeNotification), &response))
+		require.False(t, response.Data.Read)
+		indexes = append(indexes, response.Data.FileData.Index)
+	}
+	require.Equal(t, 4, indexes[0])
+	require.Equal(t, 58, indexes[49])
+
+	// CASE another user claiming the reader's ID gets none of their notifications
+	snooperID := insertSessionUser(t, "snooper")
+	token, err := jwtUtils.CreateJWT(t.Context(), "snooper", db)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Add("User-Agent", testUserAgent)
+	snooper, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	require.NoError(t, err)
+	defer snooper.Close()
+
+	send(t, snooper, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"reader","UserID":%d,"SessionID":"snooper-session"}}`, token, userID))
+	require.NoError(t, snooper.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, resp, err := snooper.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(resp, &responseObj))
+	require.Equal(t, websocket.MessageTypeAuthFailed, responseObj.Type)
+
+	// CASE the inbox sent on auth is the one of the token's user
+	send(t, snooper, fmt.Sprintf(`{"type":"auth","data":{"Token":"%s","Username":"snooper","UserID":%d,"SessionID":"snooper-session"}}`, token, snooperID))
+	_, resp, err = snooper.ReadMessage()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(resp, &responseObj))
+	require.Equal(t, websocket.MessageTypeAuthSuccess, responseObj.Type)
+
+	require.NoError(t, snooper.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = snooper.ReadMessage()
+	require.Error(t, err)
+}
+
 func insertOneUser() {
 	result := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", "test", "test123")
 	if result.Error != nil {
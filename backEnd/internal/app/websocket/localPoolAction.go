@@ -94,11 +94,12 @@ func (manager *ConnectionManager) deleteManagersMap() {
 }
 
 func (manager *ConnectionManager) DeleteSessionInDoc() {
+	manager.connections.removeSessionFromDoc(manager.currentFileUUID, manager.clientSocket.client.SessionID)
+	manager.currentFileUUID = ""
+}
 
-	docUUID := manager.currentFileUUID
-	sessionID := manager.clientSocket.client.SessionID
-
-	value, ok := manager.connections.docSessions.Load(docUUID)
+func (p *SafeConnectionPool) removeSessionFromDoc(docUUID string, sessionID string) {
+	value, ok := p.docSessions.Load(docUUID)
 	if !ok {
 		return
 	}
@@ -115,11 +116,10 @@ func (manager *ConnectionManager) DeleteSessionInDoc() {
 	newSessions = append(newSessions, sessions[idx+1:]...)
 
 	if len(newSessions) == 0 {
-		manager.connections.docSessions.Delete(docUUID)
+		p.docSessions.Delete(docUUID)
 	} else {
-		manager.connections.docSessions.Store(docUUID, newSessions)
+		p.docSessions.Store(docUUID, newSessions)
 	}
-	manager.currentFileUUID = ""
 }
 
 func searchIndex(searchSession string, sessions []string) int {
@@ -139,20 +139,38 @@ func (p *SafeConnectionPool) RouteEvent(event interface{}) {
 	case common.FileEvent:
 		p.routeToDocument(v)
 
+	case common.DocumentOperation:
+		p.routeOperationToDocument(v)
+
 	default:
 		log.Printf("Unknown notification type: %T", v)
 	}
 }
 
 func (p *SafeConnectionPool) routeToUser(notification common.UserNotification) {
+	// a user without a session here reads the notification from their inbox
 	value, ok := p.userIndex.Load(notification.TargetUser)
 	if !ok {
-		log.Println("targetUser is not in the map userIndex")
 		return
 	}
 	sessionsTargetUser := value.([]string)
+	switch notification.NotificationType {
+	case NotificationRoleChanged, NotificationOwnershipTransferred:
+		for _, sessionID := range sessionsTargetUser {
+			if managerValue, ok := p.managers.Load(sessionID); ok {
+				managerValue.(*ConnectionManager).applyRoleChange(notification)
+			}
+		}
+	case NotificationFileRevoke:
+		for _, sessionID := range sessionsTargetUser {
+			if managerValue, ok := p.managers.Load(sessionID); ok {
+				managerValue.(*ConnectionManager).kickFromFile(notification)
+			}
+		}
+	}
+
 	responseStruct := Response{
-		Type: "notification",
+		Type: MessageTypeNotification,
 		Data: notification,
 	}
 	bResponse, err := json.Marshal(responseStruct)
@@ -164,12 +182,12 @@ func (p *SafeConnectionPool) routeToUser(notification common.UserNotification) {
 		managerValue, ok := p.managers.Load(sessionID)
 		if ok {
 			manager := managerValue.(*ConnectionManager)
+			// a dropped notification stays unread in the inbox, it is sent again on the next auth
 			go func(mgr *ConnectionManager) {
 				select {
 				case mgr.send <- bResponse:
-					// Message envoyé
 				default:
-					// Channel plein, on drop
+					log.Printf("Failed to send notification to %s: channel full", mgr.clientSocket.client.SessionID)
 				}
 			}(manager)
 		}
@@ -209,6 +227,44 @@ func (p *SafeConnectionPool) routeToDocument(fileEvent common.FileEvent) {
 	}
 }
 
+func (p *SafeConnectionPool) routeOperationToDocument(operation common.DocumentOperation) {
+	value, ok := p.docSessions.Load(operation.FileUUID)
+	if !ok {
+		return
+	}
+
+	sessions := value.([]string)
+	responseStruct := Response{
+		Type: operation.OperationType,
+		Data: operation,
+	}
+	bResponse, err := json.Marshal(responseStruct)
+	if err != nil {
+		log.Println("error marshaling document operation")
+		return
+	}
+
+	for _, sessionID := range sessions {
+		// the emitting session already applied the operation locally
+		if sessionID == operation.SessionID {
+			continue
+		}
+		if managerValue, ok := p.managers.Load(sessionID); ok {
+			manager := managerValue.(*ConnectionManager)
+			// a dropped operation can't be sent again, the session is closed so the client
+			// reconnects and reloads the document instead of drifting out of sync
+			go func(mgr *ConnectionManager) {
+				select {
+				case mgr.send <- bResponse:
+				default:
+					log.Printf("Failed to send operation to %s: channel full, closing the session", mgr.clientSocket.client.SessionID)
+					mgr.cancel()
+				}
+			}(manager)
+		}
+	}
+}
+
 func Init() {
 	redisUtils.SetEventRouter(sConnectionPool)
 }
@@ -0,0 +1,108 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	websocket "github.com/evanrmtl/miniDoc/internal/app/websocket"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type operationResponse struct {
+	Type string `json:"type"`
+	Data struct {
+		OperationType string               `json:"operationType"`
+		FileUUID      string               `json:"fileUUID"`
+		SessionID     string               `json:"sessionID"`
+		UserID        uint32               `json:"userID"`
+		Revision      int64                `json:"revision"`
+		Data          common.CharacterData `json:"data"`
+	} `json:"data"`
+}
+
+func TestInsertDelete(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	require.NoError(t, gorm.G[models.File](db).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: "operations"}))
+
+	editor := joinAs(t, "editor", fileUUID, models.RoleCollaborator)
+	defer editor.Close()
+	reader := joinAs(t, "reader", fileUUID, models.RoleViewer)
+	defer reader.Close()
+
+	// CASE an insert is stored and acknowledged with its revision
+	send(t, editor, `{"type":"insert","data":{"value":"a","path":[5,0],"style":1,"color":"#ff0000"}}`)
+	var ack operationResponse
+	require.NoError(t, json.Unmarshal(readType(t, editor, websocket.MessageTypeOpAck), &ack))
+	require.Equal(t, document.OperationInsert, ack.Data.OperationType)
+	require.Equal(t, int64(1), ack.Data.Revision)
+	require.Equal(t, "editor-session", ack.Data.SessionID)
+
+	content, err := document.LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, content.Characters, 1)
+	require.Equal(t, common.CharacterData{Value: "a", Path: []int{5, 0}, Style: 1, Color: "#ff0000"}, content.Characters[0])
+
+	// CASE the operation received through Redis reaches the other sessions of the file
+	operation := common.DocumentOperation{
+		OperationType: ack.Data.OperationType,
+		FileUUID:      ack.Data.FileUUID,
+		SessionID:     ack.Data.SessionID,
+		UserID:        ack.Data.UserID,
+		Revision:      ack.Data.Revision,
+		Data:          ack.Data.Data,
+	}
+	redisUtils.HandleDocumentOperation(t.Context(), operation)
+
+	var received operationResponse
+	require.NoError(t, json.Unmarshal(readType(t, reader, document.OperationInsert), &received))
+	require.Equal(t, int64(1), received.Data.Revision)
+	require.Equal(t, []int{5, 0}, received.Data.Data.Path)
+
+	// CASE the path is allocated between the neighbours when the client gives none
+	send(t, editor, `{"type":"insert","data":{"value":"b","left":[5,0]}}`)
+	require.NoError(t, json.Unmarshal(readType(t, editor, websocket.MessageTypeOpAck), &ack))
+	require.Equal(t, int64(2), ack.Data.Revision)
+	require.NotEmpty(t, ack.Data.Data.Path)
+
+	content, err = document.LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, content.Characters, 2)
+	require.Equal(t, "b", content.Characters[1].Value)
+
+	// CASE a path that isn't made of (digit, site) pairs is refused
+	send(t, editor, `{"type":"insert","data":{"value":"c","path":[9,1,3]}}`)
+	readType(t, editor, websocket.MessageTypeOpFailed)
+
+	// CASE a viewer can't edit
+	send(t, reader, `{"type":"insert","data":{"value":"c","path":[9,0]}}`)
+	readType(t, reader, websocket.MessageTypeOpFailed)
+
+	// CASE a delete tombstones the character
+	send(t, editor, `{"type":"delete","data":{"value":"a","path":[5,0]}}`)
+	require.NoError(t, json.Unmarshal(readType(t, editor, websocket.MessageTypeOpAck), &ack))
+	require.Equal(t, document.OperationDelete, ack.Data.OperationType)
+	require.Equal(t, int64(3), ack.Data.Revision)
+
+	char, err := gorm.G[models.FilesContents](db).Where("file_uuid = ?", fileUUID).Where("char_value = ?", []byte("a")).First(t.Context())
+	require.NoError(t, err)
+	require.True(t, char.Deleted)
+	require.Equal(t, int64(3), char.DeletedRev)
+
+	// CASE deleting a character already deleted fails
+	send(t, editor, `{"type":"delete","data":{"value":"a","path":[5,0]}}`)
+	readType(t, editor, websocket.MessageTypeOpFailed)
+
+	content, err = document.LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), content.Revision)
+	require.Len(t, content.Characters, 1)
+}
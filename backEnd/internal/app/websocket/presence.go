@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+)
+
+const (
+	OperationPresenceJoin  = "presence_join"
+	OperationPresenceLeave = "presence_leave"
+	OperationCursor        = "cursor"
+)
+
+// cursorInterval is the minimum delay between two cursor messages relayed for a session,
+// the positions received in between are dropped except the last one.
+const cursorInterval = 100 * time.Millisecond
+
+type cursorThrottle struct {
+	mu      sync.Mutex
+	lastAt  time.Time
+	pending *common.CursorData
+	timer   *time.Timer
+}
+
+// joinPresence announces the session to the other sessions of the file
+// and sends it the ones already there.
+func (manager *ConnectionManager) joinPresence(sendChan chan []byte) {
+	ctx := manager.clientSocket.socket.ctx
+	presence := common.PresenceData{
+		UserID:    manager.clientSocket.client.UserID,
+		Username:  manager.clientSocket.client.Username,
+		SessionID: manager.clientSocket.client.SessionID,
+	}
+
+	storedPresences, err := redisUtils.GetPresences(manager.currentFileUUID, ctx)
+	if err != nil {
+		log.Printf("error while loading presences of %s: %v", manager.currentFileUUID, err)
+	}
+
+	presences := make([]common.PresenceData, 0, len(storedPresences))
+	for _, storedPresence := range storedPresences {
+		var other common.PresenceData
+		if json.Unmarshal([]byte(storedPresence), &other) == nil {
+			presences = append(presences, other)
+		}
+	}
+	manager.clientSocket.sendResponse(sendChan, MessageTypePresenceList, presences)
+
+	redisUtils.AddPresence(manager.currentFileUUID, presence.SessionID, presence, ctx)
+	manager.broadcastOperation(manager.newOperation(OperationPresenceJoin, 0, presence))
+}
+
+// leaveFile removes the session from its current file, locally and in Redis,
+// and tells the other sessions of the file it left.
+func (manager *ConnectionManager) leaveFile(ctx context.Context) {
+	fileUUID := manager.currentFileUUID
+	if fileUUID == "" {
+		return
+	}
+	sessionID := manager.clientSocket.client.SessionID
+
+	manager.cursor.mu.Lock()
+	if manager.cursor.timer != nil {
+		manager.cursor.timer.Stop()
+		manager.cursor.timer = nil
+	}
+	manager.cursor.pending = nil
+	manager.cursor.mu.Unlock()
+
+	operation := manager.newOperation(OperationPresenceLeave, 0, common.PresenceData{
+		UserID:    manager.clientSocket.client.UserID,
+		Username:  manager.clientSocket.client.Username,
+		SessionID: sessionID,
+	})
+
+	redisUtils.DeleteSessionRevision(fileUUID, sessionID, ctx)
+	redisUtils.DeletePresence(fileUUID, sessionID, ctx)
+	manager.DeleteSessionInDoc()
+
+	err := redisUtils.BroadcastDocumentOperation(ctx, operation)
+	if err != nil {
+		log.Printf("error while broadcasting %s operation: %v", operation.OperationType, err)
+	}
+}
+
+func (manager *ConnectionManager) handleCursor(msg []byte) {
+	var data struct {
+		Cursor common.CursorData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		log.Printf("error while unmarshall data in handleCursor: %v", err)
+		return
+	}
+
+	if manager.currentFileUUID == "" {
+		return
+	}
+
+	throttle := &manager.cursor
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	wait := cursorInterval - time.Since(throttle.lastAt)
+	if wait <= 0 && throttle.timer == nil {
+		throttle.lastAt = time.Now()
+		manager.broadcastOperation(manager.newOperation(OperationCursor, 0, data.Cursor))
+		return
+	}
+
+	throttle.pending = &data.Cursor
+	if throttle.timer == nil {
+		throttle.timer = time.AfterFunc(max(wait, 0), manager.flushCursor)
+	}
+}
+
+// flushCursor relays the last cursor position received while throttled.
+func (manager *ConnectionManager) flushCursor() {
+	throttle := &manager.cursor
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	throttle.timer = nil
+	if throttle.pending == nil || manager.currentFileUUID == "" {
+		return
+	}
+
+	throttle.lastAt = time.Now()
+	manager.broadcastOperation(manager.newOperation(OperationCursor, 0, *throttle.pending))
+	throttle.pending = nil
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+)
+
+const (
+	NotificationRoleChanged          = "role_changed"
+	NotificationOwnershipTransferred = "ownership_transferred"
+	NotificationFileRevoke           = "file_revoke"
+)
+
+// fileRole caches the role of the session's user on the joined file. It is written
+// by the read pump on join and by the pool when the owner changes the role.
+type fileRole struct {
+	mu       sync.RWMutex
+	fileUUID string
+	role     string
+}
+
+func (r *fileRole) set(fileUUID string, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fileUUID = fileUUID
+	r.role = role
+}
+
+// update changes the role only if it is still cached for this file.
+func (r *fileRole) update(fileUUID string, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fileUUID == fileUUID {
+		r.role = role
+	}
+}
+
+// revoke drops the role if it is cached for this file and reports whether it was.
+func (r *fileRole) revoke(fileUUID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fileUUID != fileUUID || r.role == "" {
+		return false
+	}
+	r.role = ""
+	return true
+}
+
+func (r *fileRole) get(fileUUID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.fileUUID != fileUUID {
+		return ""
+	}
+	return r.role
+}
+
+// canEdit reports whether the session is authenticated, has joined a file
+// and its role on it allows to change the content.
+func (manager *ConnectionManager) canEdit() bool {
+	if manager.clientSocket.client.SessionID == "" || manager.currentFileUUID == "" {
+		return false
+	}
+	return accessUtils.CanEdit(manager.role.get(manager.currentFileUUID))
+}
+
+// applyRoleChange updates the cached role when the notification targets the joined file,
+// both role changes and ownership transfers carry the new role of the user.
+func (manager *ConnectionManager) applyRoleChange(notification common.UserNotification) {
+	var data common.RoleFileData
+	if decodeFileData(notification.FileData, &data) != nil {
+		return
+	}
+	manager.role.update(data.FileUUID, data.Role)
+}
+
+// kickFromFile disconnects the session from the file its access was revoked on: it stops
+// receiving the file operations and leaves the presence list right away. The joined file
+// itself is reset by the read pump on the next message, see dropRevokedFile.
+func (manager *ConnectionManager) kickFromFile(notification common.UserNotification) {
+	var data common.RevokeFileData
+	if decodeFileData(notification.FileData, &data) != nil {
+		return
+	}
+	if !manager.role.revoke(data.FileUUID) {
+		return
+	}
+
+	ctx := context.Background()
+	sessionID := manager.clientSocket.client.SessionID
+	manager.connections.removeSessionFromDoc(data.FileUUID, sessionID)
+	redisUtils.DeleteSessionRevision(data.FileUUID, sessionID, ctx)
+	redisUtils.DeletePresence(data.FileUUID, sessionID, ctx)
+
+	operation := common.DocumentOperation{
+		OperationType: OperationPresenceLeave,
+		FileUUID:      data.FileUUID,
+		SessionID:     sessionID,
+		UserID:        manager.clientSocket.client.UserID,
+		Data: common.PresenceData{
+			UserID:    manager.clientSocket.client.UserID,
+			Username:  manager.clientSocket.client.Username,
+			SessionID: sessionID,
+		},
+	}
+	err := redisUtils.BroadcastDocumentOperation(ctx, operation)
+	if err != nil {
+		log.Printf("error while broadcasting %s operation: %v", operation.OperationType, err)
+	}
+}
+
+// dropRevokedFile forgets the joined file once kickFromFile revoked the access to it.
+func (manager *ConnectionManager) dropRevokedFile() {
+	if manager.currentFileUUID == "" || manager.role.get(manager.currentFileUUID) != "" {
+		return
+	}
+	redisUtils.DeleteFileInSession(manager.clientSocket.client.SessionID, manager.clientSocket.socket.ctx)
+	manager.currentFileUUID = ""
+}
+
+// decodeFileData reads the data of a notification, notifications received from another
+// server carry it decoded as a map.
+func decodeFileData(fileData interface{}, data interface{}) error {
+	bData, err := json.Marshal(fileData)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bData, data)
+}
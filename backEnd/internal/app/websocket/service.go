@@ -1,13 +1,18 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
 	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/notificationUtils"
 	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
 	sessionsUtils "github.com/evanrmtl/miniDoc/internal/pkg/sessionUtils"
 	"github.com/gorilla/websocket"
@@ -73,6 +78,10 @@ func (manager *ConnectionManager) readPump(db *gorm.DB) {
 	}()
 
 	websocketConnection.SetPongHandler(func(appData string) error {
+		// the pong is the heartbeat of the presence in the joined file
+		if manager.currentFileUUID != "" {
+			redisUtils.RefreshPresence(manager.currentFileUUID, manager.clientSocket.client.SessionID, manager.clientSocket.socket.ctx)
+		}
 		err := websocketConnection.SetReadDeadline(time.Now().Add(manager.readTimeout))
 		return err
 	})
@@ -102,13 +111,34 @@ func (manager *ConnectionManager) handleTextMessage(msg []byte, db *gorm.DB, sen
 		log.Println("unmarshall type webosocket impossible")
 		return
 	}
+	manager.dropRevokedFile()
 	switch messageType.Type {
 	case "auth":
 		manager.handleAuthentication(msg, db, sendChan)
 	case "joinFile":
-		manager.handleJoinFile(msg)
+		manager.handleJoinFile(msg, db, sendChan)
 	case "exitFile":
 		manager.handleExitFile()
+	case "sync":
+		manager.handleSync(msg, db, sendChan)
+	case "ack":
+		manager.handleAck(msg, db)
+	case "cursor":
+		manager.handleCursor(msg)
+	case "suggestMode":
+		manager.handleSuggestMode(msg, sendChan)
+	case document.OperationInsert:
+		manager.handleInsert(msg, db, sendChan)
+	case document.OperationDelete:
+		manager.handleDelete(msg, db, sendChan)
+	case document.OperationFormat:
+		manager.handleFormat(msg, db, sendChan)
+	case document.OperationBlock:
+		manager.handleBlock(msg, db, sendChan)
+	case document.OperationKindUndo:
+		manager.handleUndo(db, sendChan, document.Undo)
+	case document.OperationKindRedo:
+		manager.handleUndo(db, sendChan, document.Redo)
 	}
 }
 
@@ -160,6 +190,7 @@ func (manager *ConnectionManager) handleAuthentication(msg []byte, db *gorm.DB,
 		manager.clientSocket.sendResponse(sendChan, MessageTypeAuthSuccess, data)
 		manager.storeLocalConnection()
 		redisUtils.StoreSessionInRedis(manager.clientSocket.client.UserID, manager.clientSocket.client.SessionID, ctx)
+		manager.sendUnreadNotifications(ctx, db, sendChan)
 
 		return
 	}
@@ -171,9 +202,23 @@ func (manager *ConnectionManager) handleAuthentication(msg []byte, db *gorm.DB,
 	manager.clientSocket.sendResponse(sendChan, MessageTypeAuthSuccess, data)
 	manager.storeLocalConnection()
 	redisUtils.StoreSessionInRedis(manager.clientSocket.client.UserID, manager.clientSocket.client.SessionID, ctx)
+	manager.sendUnreadNotifications(ctx, db, sendChan)
 }
 
-func (manager *ConnectionManager) handleJoinFile(msg []byte) {
+// sendUnreadNotifications delivers the notifications the user received while offline,
+// they stay unread until the client marks them.
+func (manager *ConnectionManager) sendUnreadNotifications(ctx context.Context, db *gorm.DB, sendChan chan []byte) {
+	notifications, err := notificationUtils.Unread(manager.clientSocket.client.UserID, maxUnreadOnAuth, ctx, db)
+	if err != nil {
+		log.Println("error while loading unread notifications:", err)
+		return
+	}
+	for _, notification := range notifications {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeNotification, notification)
+	}
+}
+
+func (manager *ConnectionManager) handleJoinFile(msg []byte, db *gorm.DB, sendChan chan []byte) {
 	var data struct {
 		FileUUID string `json:"data"`
 	}
@@ -183,19 +228,289 @@ func (manager *ConnectionManager) handleJoinFile(msg []byte) {
 		fmt.Println("error while unmarshall data in handleJoinFile")
 		return
 	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	role, err := accessUtils.GetRole(manager.clientSocket.client.UserID, data.FileUUID, ctx, db)
+	if err != nil {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeJoinFailed, data.FileUUID)
+		return
+	}
+
+	if manager.currentFileUUID != "" {
+		manager.leaveFile(manager.clientSocket.socket.ctx)
+	}
+
 	manager.currentFileUUID = data.FileUUID
+	manager.role.set(data.FileUUID, role)
 	redisUtils.AddFileInSession(data.FileUUID, manager.clientSocket.client.SessionID, manager.clientSocket.socket.ctx)
 	manager.connections.AddSessionToDoc(data.FileUUID, manager.clientSocket.client.SessionID)
 	fmt.Println("manager.currentFileUUID: ", manager.currentFileUUID)
+
+	if !manager.sendDocumentContent(db, sendChan) {
+		manager.handleExitFile()
+		manager.clientSocket.sendResponse(sendChan, MessageTypeJoinFailed, data.FileUUID)
+		return
+	}
+	manager.joinPresence(sendChan)
+}
+
+// sendDocumentContent sends the current content of the joined file to the session,
+// which acknowledges its revision. Return false if the content couldn't be loaded.
+func (manager *ConnectionManager) sendDocumentContent(db *gorm.DB, sendChan chan []byte) bool {
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	content, err := document.LoadDocument(ctx, db, manager.currentFileUUID)
+	if err != nil {
+		log.Printf("error while loading document %s: %v", manager.currentFileUUID, err)
+		return false
+	}
+	redisUtils.SetSessionRevision(manager.currentFileUUID, manager.clientSocket.client.SessionID, content.Revision, ctx)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeDocumentContent, content)
+	return true
 }
 
 func (manager *ConnectionManager) handleExitFile() {
-	manager.DeleteSessionInDoc()
+	manager.leaveFile(manager.clientSocket.socket.ctx)
 	redisUtils.DeleteFileInSession(manager.clientSocket.client.SessionID, manager.clientSocket.socket.ctx)
 
 	fmt.Println("manager.currentFileUUID: ", manager.currentFileUUID)
 }
 
+func (manager *ConnectionManager) handleInsert(msg []byte, db *gorm.DB, sendChan chan []byte) {
+	var data struct {
+		Character common.CharacterData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleInsert")
+		return
+	}
+
+	if manager.suggesting.Load() {
+		manager.handleSuggestInsert(data.Character, db, sendChan)
+		return
+	}
+
+	if !manager.canEdit() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Character)
+		return
+	}
+
+	err = document.AllocatePath(&data.Character, manager.clientSocket.client.SessionID)
+	if err != nil {
+		log.Printf("error while allocating character path: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Character)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	revision, err := document.InsertCharacter(ctx, db, manager.author(), manager.currentFileUUID, data.Character)
+	if err != nil {
+		log.Printf("error while inserting character: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Character)
+		return
+	}
+
+	operation := manager.newOperation(document.OperationInsert, revision, data.Character)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+func (manager *ConnectionManager) handleDelete(msg []byte, db *gorm.DB, sendChan chan []byte) {
+	var data struct {
+		Character common.CharacterData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleDelete")
+		return
+	}
+
+	if manager.suggesting.Load() {
+		manager.handleSuggestDelete(data.Character, db, sendChan)
+		return
+	}
+
+	if !manager.canEdit() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Character)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	revision, err := document.DeleteCharacter(ctx, db, manager.author(), manager.currentFileUUID, data.Character.Path)
+	if err != nil {
+		log.Printf("error while deleting character: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Character)
+		return
+	}
+
+	operation := manager.newOperation(document.OperationDelete, revision, data.Character)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+func (manager *ConnectionManager) handleFormat(msg []byte, db *gorm.DB, sendChan chan []byte) {
+	var data struct {
+		Format common.FormatData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleFormat")
+		return
+	}
+
+	if !manager.canEdit() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Format)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	revision, format, err := document.FormatRange(ctx, db, manager.author(), manager.currentFileUUID, data.Format)
+	if err != nil {
+		log.Printf("error while formatting characters: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Format)
+		return
+	}
+
+	operation := manager.newOperation(document.OperationFormat, revision, format)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+func (manager *ConnectionManager) handleBlock(msg []byte, db *gorm.DB, sendChan chan []byte) {
+	var data struct {
+		Block common.BlockData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleBlock")
+		return
+	}
+
+	if !manager.canEdit() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Block)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	revision, block, err := document.SetBlock(ctx, db, manager.author(), manager.currentFileUUID, data.Block)
+	if err != nil {
+		log.Printf("error while setting block: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, data.Block)
+		return
+	}
+
+	operation := manager.newOperation(document.OperationBlock, revision, block)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+// handleUndo runs an undo or a redo of the session's own operations
+// and broadcasts the resulting operation like any edit.
+func (manager *ConnectionManager) handleUndo(db *gorm.DB, sendChan chan []byte, revert func(context.Context, *gorm.DB, document.Author, string) (common.DocumentOperation, error)) {
+	if !manager.canEdit() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, nil)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	operation, err := revert(ctx, db, manager.author(), manager.currentFileUUID)
+	if err != nil {
+		log.Printf("error while reverting operation: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, nil)
+		return
+	}
+
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+func (manager *ConnectionManager) handleSync(msg []byte, db *gorm.DB, sendChan chan []byte) {
+	var data struct {
+		Since int64 `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleSync")
+		return
+	}
+
+	if manager.currentFileUUID == "" {
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	operations, err := document.OperationsSince(ctx, db, manager.currentFileUUID, data.Since)
+	if errors.Is(err, document.ErrRevisionCompacted) {
+		manager.sendDocumentContent(db, sendChan)
+		return
+	}
+	if err != nil {
+		log.Printf("error while loading operations of %s: %v", manager.currentFileUUID, err)
+		return
+	}
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOperations, operations)
+}
+
+// handleAck records the last revision the session applied, tombstones
+// deleted before it can then be garbage-collected.
+func (manager *ConnectionManager) handleAck(msg []byte, db *gorm.DB) {
+	var data struct {
+		Revision int64 `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleAck")
+		return
+	}
+
+	if manager.currentFileUUID == "" {
+		return
+	}
+
+	// a revision ahead of the file would let the compaction fold operations the session never received
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	revision, err := document.CurrentRevision(ctx, db, manager.currentFileUUID)
+	if err != nil {
+		log.Printf("error while loading revision of %s: %v", manager.currentFileUUID, err)
+		return
+	}
+	if data.Revision < revision {
+		revision = data.Revision
+	}
+	redisUtils.SetSessionRevision(manager.currentFileUUID, manager.clientSocket.client.SessionID, revision, manager.clientSocket.socket.ctx)
+}
+
+func (manager *ConnectionManager) author() document.Author {
+	return document.Author{
+		UserID:    manager.clientSocket.client.UserID,
+		SessionID: manager.clientSocket.client.SessionID,
+	}
+}
+
+func (manager *ConnectionManager) newOperation(operationType string, revision int64, data interface{}) common.DocumentOperation {
+	return common.DocumentOperation{
+		OperationType: operationType,
+		FileUUID:      manager.currentFileUUID,
+		SessionID:     manager.clientSocket.client.SessionID,
+		UserID:        manager.clientSocket.client.UserID,
+		Revision:      revision,
+		Data:          data,
+	}
+}
+
+func (manager *ConnectionManager) broadcastOperation(operation common.DocumentOperation) {
+	err := redisUtils.BroadcastDocumentOperation(manager.clientSocket.socket.ctx, operation)
+	if err != nil {
+		log.Printf("error while broadcasting %s operation: %v", operation.OperationType, err)
+	}
+}
+
 func (cs *ClientSocket) sendResponse(sendChan chan []byte, msgType string, data interface{}) {
 	response := Response{
 		Type: msgType,
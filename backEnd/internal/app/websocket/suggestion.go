@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"gorm.io/gorm"
+)
+
+type SuggestModeData struct {
+	Enabled bool `json:"enabled"`
+}
+
+// handleSuggestMode switches the session between editing and suggesting, in suggesting mode
+// its inserts and deletes are recorded as suggestions instead of being applied.
+func (manager *ConnectionManager) handleSuggestMode(msg []byte, sendChan chan []byte) {
+	var data struct {
+		Mode SuggestModeData `json:"data"`
+	}
+
+	err := json.Unmarshal(msg, &data)
+	if err != nil {
+		fmt.Println("error while unmarshall data in handleSuggestMode")
+		return
+	}
+
+	manager.suggesting.Store(data.Mode.Enabled)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeSuggestMode, data.Mode)
+}
+
+// canSuggest reports whether the session has joined a file and its role on it allows
+// to suggest, which is the right to comment.
+func (manager *ConnectionManager) canSuggest() bool {
+	if manager.clientSocket.client.SessionID == "" || manager.currentFileUUID == "" {
+		return false
+	}
+	return accessUtils.CanComment(manager.role.get(manager.currentFileUUID))
+}
+
+func (manager *ConnectionManager) handleSuggestInsert(char common.CharacterData, db *gorm.DB, sendChan chan []byte) {
+	if !manager.canSuggest() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, char)
+		return
+	}
+
+	err := document.AllocatePath(&char, manager.clientSocket.client.SessionID)
+	if err != nil {
+		log.Printf("error while allocating character path: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, char)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	suggestion, err := document.SuggestInsert(ctx, db, manager.author(), manager.currentFileUUID, char)
+	if err != nil {
+		log.Printf("error while suggesting insert: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, char)
+		return
+	}
+
+	operation := manager.newOperation(document.SuggestionCreated, 0, suggestion)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
+
+func (manager *ConnectionManager) handleSuggestDelete(char common.CharacterData, db *gorm.DB, sendChan chan []byte) {
+	if !manager.canSuggest() {
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, char)
+		return
+	}
+
+	ctx := manager.clientSocket.socket.ctx.Request.Context()
+	suggestion, err := document.SuggestDelete(ctx, db, manager.author(), manager.currentFileUUID, char.Path)
+	if err != nil {
+		log.Printf("error while suggesting delete: %v", err)
+		manager.clientSocket.sendResponse(sendChan, MessageTypeOpFailed, char)
+		return
+	}
+
+	// deleting a character of an own suggested insert withdraws it
+	event := document.SuggestionCreated
+	if suggestion.Status == models.SuggestionRejected {
+		event = document.SuggestionRejected
+	}
+	operation := manager.newOperation(event, 0, suggestion)
+	manager.clientSocket.sendResponse(sendChan, MessageTypeOpAck, operation)
+	manager.broadcastOperation(operation)
+}
@@ -0,0 +1,90 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	websocket "github.com/evanrmtl/miniDoc/internal/app/websocket"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type suggestionResponse struct {
+	Type string `json:"type"`
+	Data struct {
+		OperationType string              `json:"operationType"`
+		Data          document.Suggestion `json:"data"`
+	} `json:"data"`
+}
+
+// joinAs authenticates a new connection of the user and joins the file with the given role.
+func joinAs(t *testing.T, username string, fileUUID string, role string) *gorillaws.Conn {
+	userID := insertSessionUser(t, username)
+	require.NoError(t, gorm.G[models.UsersFile](testenv.DB).Create(t.Context(), &models.UsersFile{UserID: userID, FileUUID: fileUUID, Role: role}))
+
+	ws := authenticate(t, username, userID)
+	send(t, ws, fmt.Sprintf(`{"type":"joinFile","data":"%s"}`, fileUUID))
+	readType(t, ws, websocket.MessageTypeDocumentContent)
+	return ws
+}
+
+func TestSuggestMode(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	require.NoError(t, gorm.G[models.File](db).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: "suggestions"}))
+
+	commenter := joinAs(t, "commenter", fileUUID, models.RoleCommenter)
+	defer commenter.Close()
+	viewer := joinAs(t, "viewer", fileUUID, models.RoleViewer)
+	defer viewer.Close()
+
+	send(t, commenter, `{"type":"suggestMode","data":{"enabled":true}}`)
+	readType(t, commenter, websocket.MessageTypeSuggestMode)
+
+	// CASE an insert in suggesting mode is recorded as a suggestion
+	send(t, commenter, `{"type":"insert","data":{"value":"a","path":[5,0]}}`)
+	var response suggestionResponse
+	require.NoError(t, json.Unmarshal(readType(t, commenter, websocket.MessageTypeOpAck), &response))
+	require.Equal(t, document.SuggestionCreated, response.Data.OperationType)
+	require.Equal(t, document.OperationInsert, response.Data.Data.Kind)
+
+	suggestions, err := document.ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	content, err := document.LoadDocument(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Empty(t, content.Characters)
+
+	// CASE deleting the own suggested insert withdraws it
+	send(t, commenter, `{"type":"delete","data":{"value":"a","path":[5,0]}}`)
+	require.NoError(t, json.Unmarshal(readType(t, commenter, websocket.MessageTypeOpAck), &response))
+	require.Equal(t, document.SuggestionRejected, response.Data.OperationType)
+
+	suggestions, err = document.ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Empty(t, suggestions)
+
+	// CASE a suggestion is already pending on the character
+	send(t, commenter, `{"type":"insert","data":{"value":"b","path":[6,0]}}`)
+	readType(t, commenter, websocket.MessageTypeOpAck)
+	_, err = document.SuggestInsert(t.Context(), db, document.Author{UserID: 1, SessionID: "other"}, fileUUID, common.CharacterData{Value: "c", Path: []int{6, 0}})
+	require.ErrorIs(t, err, document.ErrSuggestionPending)
+
+	// CASE a viewer can't suggest
+	send(t, viewer, `{"type":"suggestMode","data":{"enabled":true}}`)
+	readType(t, viewer, websocket.MessageTypeSuggestMode)
+	send(t, viewer, `{"type":"insert","data":{"value":"d","path":[7,0]}}`)
+	readType(t, viewer, websocket.MessageTypeOpFailed)
+
+	suggestions, err = document.ListSuggestions(t.Context(), db, fileUUID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+}
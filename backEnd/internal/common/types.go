@@ -2,9 +2,12 @@ package common
 
 type UserNotification struct {
 	ServerName       string      `json:"serverName"`
+	NotificationID   string      `json:"notificationID"`
 	NotificationType string      `json:"notificationType"`
 	TargetUser       uint32      `json:"targetUser"`
 	FileData         interface{} `json:"fileData"`
+	Read             bool        `json:"read"`
+	CreatedAt        int64       `json:"createdAt"`
 }
 
 type ShareFileData struct {
@@ -23,11 +26,114 @@ type RevokeFileData struct {
 	FileUUID string `json:"fileUUID"`
 }
 
+type RoleFileData struct {
+	FileUUID string `json:"fileUUID"`
+	Role     string `json:"role"`
+}
+
+// AccessRequestData tells the owners of a file someone asks to access it.
+type AccessRequestData struct {
+	RequestID string `json:"requestID"`
+	FileUUID  string `json:"fileUUID"`
+	FileName  string `json:"fileName"`
+	Username  string `json:"username"`
+	Message   string `json:"message"`
+}
+
+// AccessResolvedData tells the requester whether their access request was approved, and with which role.
+type AccessResolvedData struct {
+	RequestID string `json:"requestID"`
+	FileUUID  string `json:"fileUUID"`
+	FileName  string `json:"fileName"`
+	Status    string `json:"status"`
+	Role      string `json:"role"`
+}
+
+// MentionData tells a user they were mentioned in a comment of the file.
+type MentionData struct {
+	FileUUID  string `json:"fileUUID"`
+	ThreadID  string `json:"threadID"`
+	CommentID string `json:"commentID"`
+	Author    string `json:"author"`
+	Excerpt   string `json:"excerpt"`
+}
+
+// WorkspaceData tells a user they joined a workspace and with which role.
+type WorkspaceData struct {
+	WorkspaceID string `json:"workspaceID"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+}
+
+// OwnershipFileData tells each user of a transfer the role they now have on the file.
+type OwnershipFileData struct {
+	FileUUID      string `json:"fileUUID"`
+	Role          string `json:"role"`
+	PreviousOwner string `json:"previousOwner"`
+	NewOwner      string `json:"newOwner"`
+}
+
 type FileEvent struct {
 	ServerName string `json:"serverName"`
 	EventType  string `json:"eventType"`
 	FileUUID   string `json:"fileData"`
 }
+
+type DocumentOperation struct {
+	ServerName    string      `json:"serverName"`
+	OperationType string      `json:"operationType"`
+	FileUUID      string      `json:"fileUUID"`
+	SessionID     string      `json:"sessionID"`
+	UserID        uint32      `json:"userID"`
+	Revision      int64       `json:"revision"`
+	Data          interface{} `json:"data"`
+}
+
+type CharacterData struct {
+	Value string `json:"value"`
+	Path  []int  `json:"path"`
+	Style uint32 `json:"style"`
+	Color string `json:"color"`
+	// block attributes, only on newline characters
+	Block    string `json:"block,omitempty"`
+	Level    int    `json:"level,omitempty"`
+	Language string `json:"language,omitempty"`
+	Left     []int  `json:"left,omitempty"`
+	Right    []int  `json:"right,omitempty"`
+}
+
+type FormatData struct {
+	From       []int           `json:"from"`
+	To         []int           `json:"to"`
+	Set        uint32          `json:"set"`
+	Clear      uint32          `json:"clear"`
+	Color      *string         `json:"color,omitempty"`
+	Timestamp  int64           `json:"timestamp"`
+	Characters []CharacterData `json:"characters,omitempty"`
+	Previous   []CharacterData `json:"previous,omitempty"`
+}
+
+type BlockData struct {
+	Path      []int          `json:"path"`
+	Block     string         `json:"block"`
+	Level     int            `json:"level"`
+	Language  string         `json:"language,omitempty"`
+	Timestamp int64          `json:"timestamp"`
+	Previous  *CharacterData `json:"previous,omitempty"`
+}
+
+type PresenceData struct {
+	UserID    uint32 `json:"userID"`
+	Username  string `json:"username"`
+	SessionID string `json:"sessionID"`
+}
+
+// CursorData is the caret position of a session, with the other end of its selection if any.
+type CursorData struct {
+	Position []int `json:"position"`
+	Anchor   []int `json:"anchor,omitempty"`
+}
+
 type NotificationRouter interface {
 	RouteEvent(notification interface{})
 }
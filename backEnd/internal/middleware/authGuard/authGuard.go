@@ -0,0 +1,147 @@
+package authGuard
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+const (
+	keyUserID   = "authGuard.userID"
+	keyFileUUID = "authGuard.fileUUID"
+	keyRole     = "authGuard.role"
+)
+
+// Authenticate validates the Bearer token of the request and stores its user in the context.
+// An expired token of a still valid session is answered with a renewed one and 409,
+// as the clients expect.
+func Authenticate(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		bearer := c.Request.Header.Get("Authorization")
+		token := strings.TrimPrefix(bearer, "Bearer ")
+		err := jwtUtils.ValidJWT(token, c.Request.UserAgent(), ctx, db)
+
+		if err != nil && !errors.Is(err, jwtUtils.ErrTokenExpired) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err})
+			return
+		}
+
+		if err != nil {
+			tokenExpiredValidSession(token, c, db)
+			return
+		}
+
+		userID, err := jwtUtils.GetUserID(token, ctx, db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err})
+			return
+		}
+
+		c.Set(keyUserID, userID)
+		c.Next()
+	}
+}
+
+func tokenExpiredValidSession(token string, c *gin.Context, db *gorm.DB) {
+	ctx := c.Request.Context()
+
+	username, err := jwtUtils.GetUsername(token, ctx, db)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err})
+		return
+	}
+	newJWT, err := jwtUtils.CreateJWT(ctx, username, db)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+		"error":  jwtUtils.ErrTokenExpired,
+		"newJWT": newJWT,
+	})
+}
+
+// RequireFileRole must follow Authenticate. It loads the role of the user on the file_uuid
+// given in the query or the JSON body and rejects the request with 404 if the file doesn't
+// exist, 403 if the user has no access or none of the given roles. Any role passes when none
+// is given, and a request whose query and body name different files is rejected with 400.
+// The body is read with ShouldBindBodyWith, handlers must bind it the same way, and take
+// the file from FileUUID rather than from their request.
+func RequireFileRole(db *gorm.DB, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		fileUUID := c.Query("file_uuid")
+		var body struct {
+			FileUUID string `json:"file_uuid"`
+		}
+		c.ShouldBindBodyWith(&body, binding.JSON)
+		if fileUUID == "" {
+			fileUUID = body.FileUUID
+		}
+		// the role is checked on one file, a handler must never act on another one
+		if body.FileUUID != "" && body.FileUUID != fileUUID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "file_uuid of the query and the body differ"})
+			return
+		}
+		if fileUUID == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Missing file_uuid"})
+			return
+		}
+
+		nbFile, err := gorm.G[models.File](db).Where("file_uuid = ?", fileUUID).Count(ctx, "file_uuid")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err})
+			return
+		}
+		if nbFile == 0 {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		role, err := accessUtils.GetRole(UserID(c), fileUUID, ctx, db)
+		if errors.Is(err, accessUtils.ErrNoAccess) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You don't have acces to this file"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err})
+			return
+		}
+		if len(roles) > 0 && !slices.Contains(roles, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Your role doesn't allow this action"})
+			return
+		}
+
+		c.Set(keyFileUUID, fileUUID)
+		c.Set(keyRole, role)
+		c.Next()
+	}
+}
+
+// UserID returns the user authenticated by Authenticate.
+func UserID(c *gin.Context) uint32 {
+	userID, _ := c.Get(keyUserID)
+	id, _ := userID.(uint32)
+	return id
+}
+
+// FileUUID returns the file checked by RequireFileRole.
+func FileUUID(c *gin.Context) string {
+	return c.GetString(keyFileUUID)
+}
+
+// Role returns the role of the user on the file checked by RequireFileRole.
+func Role(c *gin.Context) string {
+	return c.GetString(keyRole)
+}
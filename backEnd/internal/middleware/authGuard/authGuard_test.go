@@ -0,0 +1,156 @@
+package authGuard_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/middleware/subroute"
+	"github.com/evanrmtl/miniDoc/internal/pkg/jwtUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	testenv "github.com/evanrmtl/miniDoc/testEnv"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	setupTestRS256KeyPair()
+
+	err := testenv.Setup()
+	if err != nil {
+		panic(err)
+	}
+	redisUtils.CreateRedis(context.Background())
+
+	code := m.Run()
+
+	testenv.Teardown()
+	os.Exit(code)
+}
+
+func createTestRoute() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	subroute.CreateFileRoutes(r.Group("/v1"), testenv.DB)
+	return r
+}
+
+// insertUser creates the user and returns their ID with a valid token.
+func insertUser(t *testing.T, username string) (uint32, string) {
+	err := testenv.DB.Exec("INSERT INTO users (username, password_hash) VALUES (?, ?)", username, "test123").Error
+	require.NoError(t, err)
+	user, err := gorm.G[models.User](testenv.DB).Where("username = ?", username).First(t.Context())
+	require.NoError(t, err)
+	token, err := jwtUtils.CreateJWT(t.Context(), username, testenv.DB)
+	require.NoError(t, err)
+	return user.UserID, token
+}
+
+func insertOwnedFile(t *testing.T, fileUUID string, ownerID uint32) {
+	require.NoError(t, gorm.G[models.File](testenv.DB).Create(t.Context(), &models.File{FileUUID: fileUUID, FileName: fileUUID}))
+	require.NoError(t, gorm.G[models.UsersFile](testenv.DB).Create(t.Context(), &models.UsersFile{UserID: ownerID, FileUUID: fileUUID, Role: models.RoleOwner}))
+}
+
+func request(router *gin.Engine, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	writer := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(writer, req)
+	return writer
+}
+
+func TestRequireFileRole(t *testing.T) {
+	testenv.CleanTables()
+	db := testenv.DB
+	router := createTestRoute()
+
+	ownFile := "11111111-1111-1111-1111-111111111111"
+	victimFile := "22222222-2222-2222-2222-222222222222"
+	attackerID, attackerToken := insertUser(t, "attacker")
+	victimID, victimToken := insertUser(t, "victim")
+	insertOwnedFile(t, ownFile, attackerID)
+	insertOwnedFile(t, victimFile, victimID)
+
+	accessOn := func(userID uint32, fileUUID string) int64 {
+		count, err := gorm.G[models.UsersFile](db).Where("user_id = ?", userID).Where("file_uuid = ?", fileUUID).Count(t.Context(), "user_id")
+		require.NoError(t, err)
+		return count
+	}
+
+	// CASE role checked on the query file, body naming another file
+	body := fmt.Sprintf(`{"file_uuid": "%s", "usernames": ["attacker"]}`, victimFile)
+	writer := request(router, http.MethodPost, "/v1/file/share?file_uuid="+ownFile, attackerToken, body)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	require.Equal(t, int64(0), accessOn(attackerID, victimFile))
+
+	// CASE same mismatch on a role change
+	body = fmt.Sprintf(`{"file_uuid": "%s", "username": "attacker", "role": "Viewer"}`, victimFile)
+	writer = request(router, http.MethodPut, "/v1/file/changeRole?file_uuid="+ownFile, attackerToken, body)
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+
+	// CASE file only in the body, checked on it
+	body = fmt.Sprintf(`{"file_uuid": "%s", "usernames": ["attacker"]}`, victimFile)
+	writer = request(router, http.MethodPost, "/v1/file/share", attackerToken, body)
+	require.Equal(t, http.StatusForbidden, writer.Code)
+	require.Equal(t, int64(0), accessOn(attackerID, victimFile))
+
+	// CASE file only in the query, the handler acts on it
+	writer = request(router, http.MethodPost, "/v1/file/share?file_uuid="+ownFile, attackerToken, `{"usernames": ["victim"]}`)
+	require.Less(t, writer.Code, http.StatusBadRequest)
+	require.Equal(t, int64(1), accessOn(victimID, ownFile))
+
+	// CASE same file in the query and the body
+	body = fmt.Sprintf(`{"file_uuid": "%s", "username": "victim", "role": "Viewer"}`, ownFile)
+	writer = request(router, http.MethodPut, "/v1/file/changeRole?file_uuid="+ownFile, attackerToken, body)
+	require.Equal(t, http.StatusOK, writer.Code)
+
+	// CASE the role on the file is still checked
+	writer = request(router, http.MethodPut, "/v1/file/changeRole?file_uuid="+ownFile, victimToken, body)
+	require.Equal(t, http.StatusForbidden, writer.Code)
+
+	// CASE missing and unknown files
+	writer = request(router, http.MethodGet, "/v1/file/content", attackerToken, "")
+	require.Equal(t, http.StatusBadRequest, writer.Code)
+	writer = request(router, http.MethodGet, "/v1/file/content?file_uuid=33333333-3333-3333-3333-333333333333", attackerToken, "")
+	require.Equal(t, http.StatusNotFound, writer.Code)
+}
+
+func setupTestRS256KeyPair() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  privateRSA key: %v", err))
+	}
+
+	privateKeyPEM := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+	var privateKeyBuf bytes.Buffer
+	pem.Encode(&privateKeyBuf, privateKeyPEM)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to generate  publicRSA key: %v", err))
+	}
+	publicKeyPEM := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	}
+	var publicKeyBuf bytes.Buffer
+	pem.Encode(&publicKeyBuf, publicKeyPEM)
+
+	os.Setenv("RS256_PRIVATE_KEY", privateKeyBuf.String())
+	os.Setenv("RS256_PUBLIC_KEY", publicKeyBuf.String())
+}
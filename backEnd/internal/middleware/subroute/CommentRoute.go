@@ -0,0 +1,42 @@
+package subroute
+
+import (
+	comment "github.com/evanrmtl/miniDoc/internal/app/Comment"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CreateCommentRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	commentRoutes := router.Group("/comment")
+	commentRoutes.Use(authGuard.Authenticate(db))
+
+	anyRole := authGuard.RequireFileRole(db)
+	canComment := authGuard.RequireFileRole(db, models.RoleOwner, models.RoleCollaborator, models.RoleCommenter)
+
+	commentRoutes.GET("/list", anyRole, func(c *gin.Context) {
+		comment.GetThreadsController(c, db)
+	})
+
+	commentRoutes.POST("/create", canComment, func(c *gin.Context) {
+		comment.CreateThreadController(c, db)
+	})
+
+	commentRoutes.POST("/reply", canComment, func(c *gin.Context) {
+		comment.ReplyController(c, db)
+	})
+
+	commentRoutes.PUT("/resolve", canComment, func(c *gin.Context) {
+		comment.ResolveThreadController(c, db)
+	})
+
+	commentRoutes.PUT("/reopen", canComment, func(c *gin.Context) {
+		comment.ReopenThreadController(c, db)
+	})
+
+	// a user can delete their own comments even after losing the right to comment
+	commentRoutes.DELETE("/delete", anyRole, func(c *gin.Context) {
+		comment.DeleteCommentController(c, db)
+	})
+}
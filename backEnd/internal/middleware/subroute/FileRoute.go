@@ -2,18 +2,24 @@ package subroute
 
 import (
 	file "github.com/evanrmtl/miniDoc/internal/app/File"
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 func CreateFileRoutes(router *gin.RouterGroup, db *gorm.DB) {
 	docGroup := router.Group("/file")
+	docGroup.Use(authGuard.Authenticate(db))
+
+	anyRole := authGuard.RequireFileRole(db)
+	ownerOnly := authGuard.RequireFileRole(db, models.RoleOwner)
 
 	docGroup.POST("/create", func(c *gin.Context) {
 		file.CreateFileController(c, db)
 	})
 
-	docGroup.DELETE("/delete", func(c *gin.Context) {
+	docGroup.DELETE("/delete", anyRole, func(c *gin.Context) {
 		file.DeleteFileController(c, db)
 	})
 
@@ -21,15 +27,110 @@ func CreateFileRoutes(router *gin.RouterGroup, db *gorm.DB) {
 		file.GetFileController(c, db)
 	})
 
-	docGroup.POST("/share", func(c *gin.Context) {
+	docGroup.GET("/content", anyRole, func(c *gin.Context) {
+		file.GetFileContentController(c, db)
+	})
+
+	docGroup.GET("/operations", anyRole, func(c *gin.Context) {
+		file.GetFileOperationsController(c, db)
+	})
+
+	docGroup.GET("/export", anyRole, func(c *gin.Context) {
+		file.ExportFileController(c, db)
+	})
+
+	docGroup.GET("/diff", anyRole, func(c *gin.Context) {
+		file.GetFileDiffController(c, db)
+	})
+
+	docGroup.POST("/version/create", ownerOnly, func(c *gin.Context) {
+		file.CreateVersionController(c, db)
+	})
+
+	docGroup.GET("/version/list", ownerOnly, func(c *gin.Context) {
+		file.GetVersionsController(c, db)
+	})
+
+	// versions are addressed by their ID, the owner is checked by the controllers
+	docGroup.GET("/version/get", func(c *gin.Context) {
+		file.GetVersionController(c, db)
+	})
+
+	docGroup.POST("/version/restore", func(c *gin.Context) {
+		file.RestoreVersionController(c, db)
+	})
+
+	docGroup.GET("/suggestion/list", anyRole, func(c *gin.Context) {
+		file.GetSuggestionsController(c, db)
+	})
+
+	docGroup.POST("/suggestion/accept", anyRole, func(c *gin.Context) {
+		file.AcceptSuggestionController(c, db)
+	})
+
+	docGroup.POST("/suggestion/reject", anyRole, func(c *gin.Context) {
+		file.RejectSuggestionController(c, db)
+	})
+
+	docGroup.POST("/share", ownerOnly, func(c *gin.Context) {
 		file.ShareFileController(c, db)
 	})
 
-	docGroup.GET("/getSharedUser", func(c *gin.Context) {
+	docGroup.PUT("/changeRole", ownerOnly, func(c *gin.Context) {
+		file.ChangeRoleController(c, db)
+	})
+
+	docGroup.POST("/transferOwnership", ownerOnly, func(c *gin.Context) {
+		file.TransferOwnershipController(c, db)
+	})
+
+	docGroup.POST("/invite/create", ownerOnly, func(c *gin.Context) {
+		file.CreateInviteController(c, db)
+	})
+
+	docGroup.GET("/invite/list", ownerOnly, func(c *gin.Context) {
+		file.GetInvitesController(c, db)
+	})
+
+	docGroup.DELETE("/invite/revoke", ownerOnly, func(c *gin.Context) {
+		file.RevokeInviteController(c, db)
+	})
+
+	// the user redeeming the link has no role on the file yet
+	docGroup.POST("/invite/redeem", func(c *gin.Context) {
+		file.RedeemInviteController(c, db)
+	})
+
+	// the user requesting an access has no role on the file
+	docGroup.POST("/access/request", func(c *gin.Context) {
+		file.RequestAccessController(c, db)
+	})
+
+	docGroup.GET("/access/list", ownerOnly, func(c *gin.Context) {
+		file.GetAccessRequestsController(c, db)
+	})
+
+	docGroup.POST("/access/resolve", ownerOnly, func(c *gin.Context) {
+		file.ResolveAccessRequestController(c, db)
+	})
+
+	docGroup.POST("/shareGroup", ownerOnly, func(c *gin.Context) {
+		file.ShareGroupController(c, db)
+	})
+
+	docGroup.GET("/getSharedGroups", anyRole, func(c *gin.Context) {
+		file.GetSharedGroupsController(c, db)
+	})
+
+	docGroup.DELETE("/removeGroup", ownerOnly, func(c *gin.Context) {
+		file.RemovedGroupController(c, db)
+	})
+
+	docGroup.GET("/getSharedUser", anyRole, func(c *gin.Context) {
 		file.GetSharedUserController(c, db)
 	})
 
-	docGroup.DELETE("/removeUser", func(c *gin.Context) {
+	docGroup.DELETE("/removeUser", ownerOnly, func(c *gin.Context) {
 		file.RemovedUserController(c, db)
 	})
 }
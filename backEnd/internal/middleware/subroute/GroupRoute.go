@@ -0,0 +1,41 @@
+package subroute
+
+import (
+	group "github.com/evanrmtl/miniDoc/internal/app/Group"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CreateGroupRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	groupRoutes := router.Group("/group")
+	groupRoutes.Use(authGuard.Authenticate(db))
+
+	groupRoutes.POST("/create", func(c *gin.Context) {
+		group.CreateGroupController(c, db)
+	})
+
+	groupRoutes.GET("/get", func(c *gin.Context) {
+		group.GetGroupsController(c, db)
+	})
+
+	groupRoutes.DELETE("/delete", func(c *gin.Context) {
+		group.DeleteGroupController(c, db)
+	})
+
+	groupRoutes.GET("/members", func(c *gin.Context) {
+		group.GetGroupMembersController(c, db)
+	})
+
+	groupRoutes.POST("/addMember", func(c *gin.Context) {
+		group.AddGroupMemberController(c, db)
+	})
+
+	groupRoutes.PUT("/changeMemberRole", func(c *gin.Context) {
+		group.ChangeGroupMemberRoleController(c, db)
+	})
+
+	groupRoutes.DELETE("/removeMember", func(c *gin.Context) {
+		group.RemoveGroupMemberController(c, db)
+	})
+}
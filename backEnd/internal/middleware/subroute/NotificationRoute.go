@@ -0,0 +1,25 @@
+package subroute
+
+import (
+	notification "github.com/evanrmtl/miniDoc/internal/app/Notification"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CreateNotificationRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	notificationRoutes := router.Group("/notification")
+	notificationRoutes.Use(authGuard.Authenticate(db))
+
+	notificationRoutes.GET("/list", func(c *gin.Context) {
+		notification.GetNotificationsController(c, db)
+	})
+
+	notificationRoutes.PUT("/read", func(c *gin.Context) {
+		notification.MarkReadController(c, db)
+	})
+
+	notificationRoutes.PUT("/readAll", func(c *gin.Context) {
+		notification.MarkAllReadController(c, db)
+	})
+}
@@ -0,0 +1,37 @@
+package subroute
+
+import (
+	workspace "github.com/evanrmtl/miniDoc/internal/app/Workspace"
+	"github.com/evanrmtl/miniDoc/internal/middleware/authGuard"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CreateWorkspaceRoutes(router *gin.RouterGroup, db *gorm.DB) {
+	workspaceRoutes := router.Group("/workspace")
+	workspaceRoutes.Use(authGuard.Authenticate(db))
+
+	workspaceRoutes.POST("/create", func(c *gin.Context) {
+		workspace.CreateWorkspaceController(c, db)
+	})
+
+	workspaceRoutes.GET("/get", func(c *gin.Context) {
+		workspace.GetWorkspacesController(c, db)
+	})
+
+	workspaceRoutes.GET("/members", func(c *gin.Context) {
+		workspace.GetWorkspaceMembersController(c, db)
+	})
+
+	workspaceRoutes.POST("/addMember", func(c *gin.Context) {
+		workspace.AddWorkspaceMemberController(c, db)
+	})
+
+	workspaceRoutes.DELETE("/removeMember", func(c *gin.Context) {
+		workspace.RemoveWorkspaceMemberController(c, db)
+	})
+
+	workspaceRoutes.GET("/files", func(c *gin.Context) {
+		workspace.GetWorkspaceFilesController(c, db)
+	})
+}
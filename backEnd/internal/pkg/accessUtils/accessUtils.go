@@ -0,0 +1,126 @@
+package accessUtils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"gorm.io/gorm"
+)
+
+var ErrNoAccess = errors.New("user has no access to this file")
+
+// roleRank orders the roles from the most to the least privileged.
+var roleRank = map[string]int{
+	models.RoleOwner:        4,
+	models.RoleCollaborator: 3,
+	models.RoleCommenter:    2,
+	models.RoleViewer:       1,
+}
+
+// GetRole returns the effective role of the user on the file, the highest of their own access,
+// of the accesses of their groups and of the default role of the workspace owning the file.
+// ErrNoAccess is returned if the file isn't shared with them.
+// An expired access is refused even before the sweeper removes it.
+func GetRole(userID uint32, fileUUID string, ctx context.Context, db *gorm.DB) (string, error) {
+	var roles []string
+	err := db.WithContext(ctx).Model(&models.UsersFile{}).
+		Where("user_id = ?", userID).
+		Where("file_uuid = ?", fileUUID).
+		Where("expires_at = 0 OR expires_at > ?", time.Now().Unix()).
+		Pluck("role", &roles).Error
+	if err != nil {
+		return "", err
+	}
+
+	var groupRoles []string
+	err = db.WithContext(ctx).Model(&models.GroupFile{}).
+		Joins("JOIN group_members ON group_members.group_id = group_files.group_id").
+		Where("group_members.user_id = ?", userID).
+		Where("group_files.file_uuid = ?", fileUUID).
+		Pluck("group_files.role", &groupRoles).Error
+	if err != nil {
+		return "", err
+	}
+
+	var workspaceRoles []string
+	err = db.WithContext(ctx).Model(&models.File{}).
+		Joins("JOIN workspaces ON workspaces.workspace_id = files.workspace_id").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = files.workspace_id").
+		Where("workspace_members.user_id = ?", userID).
+		Where("files.file_uuid = ?", fileUUID).
+		Pluck("workspaces.default_role", &workspaceRoles).Error
+	if err != nil {
+		return "", err
+	}
+
+	roles = append(roles, groupRoles...)
+	role := HighestRole(append(roles, workspaceRoles...)...)
+	if role == "" {
+		return "", ErrNoAccess
+	}
+	return role, nil
+}
+
+// FileUUIDs returns every file the user has access to, directly, through a group or a workspace.
+func FileUUIDs(userID uint32, ctx context.Context, db *gorm.DB) ([]string, error) {
+	var fileUUIDs []string
+	err := db.WithContext(ctx).Raw(`
+		SELECT file_uuid FROM users_files WHERE user_id = ? AND (expires_at = 0 OR expires_at > ?)
+		UNION
+		SELECT group_files.file_uuid FROM group_files
+		JOIN group_members ON group_members.group_id = group_files.group_id
+		WHERE group_members.user_id = ?
+		UNION
+		SELECT files.file_uuid FROM files
+		JOIN workspace_members ON workspace_members.workspace_id = files.workspace_id
+		WHERE workspace_members.user_id = ?`, userID, time.Now().Unix(), userID, userID).
+		Scan(&fileUUIDs).Error
+	return fileUUIDs, err
+}
+
+// GroupMemberIDs returns the users of the group, who all get the accesses of the group.
+func GroupMemberIDs(groupID string, ctx context.Context, db *gorm.DB) ([]uint32, error) {
+	var userIDs []uint32
+	err := db.WithContext(ctx).Model(&models.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// WorkspaceMemberIDs returns the users of the workspace, who all get the default access on its files.
+func WorkspaceMemberIDs(workspaceID string, ctx context.Context, db *gorm.DB) ([]uint32, error) {
+	var userIDs []uint32
+	err := db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ?", workspaceID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// HighestRole returns the most privileged of the roles, "" if there is none.
+func HighestRole(roles ...string) string {
+	highest := ""
+	for _, role := range roles {
+		if roleRank[role] > roleRank[highest] {
+			highest = role
+		}
+	}
+	return highest
+}
+
+// CanEdit reports whether the role allows to change the content of the file.
+func CanEdit(role string) bool {
+	return role == models.RoleOwner || role == models.RoleCollaborator
+}
+
+// CanComment reports whether the role allows to comment the file.
+func CanComment(role string) bool {
+	return CanEdit(role) || role == models.RoleCommenter
+}
+
+// IsShareRole reports whether the role can be given when sharing a file,
+// a file only has one owner.
+func IsShareRole(role string) bool {
+	return role == models.RoleCollaborator || role == models.RoleCommenter || role == models.RoleViewer
+}
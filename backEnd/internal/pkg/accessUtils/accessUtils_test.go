@@ -0,0 +1,43 @@
+package accessUtils
+
+import (
+	"testing"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolePermissions(t *testing.T) {
+	// CASE owner and collaborator edit and comment
+	require.True(t, CanEdit(models.RoleOwner))
+	require.True(t, CanEdit(models.RoleCollaborator))
+	require.True(t, CanComment(models.RoleCollaborator))
+
+	// CASE commenter only comments
+	require.False(t, CanEdit(models.RoleCommenter))
+	require.True(t, CanComment(models.RoleCommenter))
+
+	// CASE viewer only reads
+	require.False(t, CanEdit(models.RoleViewer))
+	require.False(t, CanComment(models.RoleViewer))
+
+	// CASE no role
+	require.False(t, CanEdit(""))
+	require.False(t, CanComment(""))
+
+	// CASE ownership can't be shared
+	require.False(t, IsShareRole(models.RoleOwner))
+	require.True(t, IsShareRole(models.RoleViewer))
+}
+
+func TestHighestRole(t *testing.T) {
+	// CASE a group gives more than the direct access
+	require.Equal(t, models.RoleCollaborator, HighestRole(models.RoleViewer, models.RoleCollaborator, models.RoleCommenter))
+
+	// CASE owner always wins
+	require.Equal(t, models.RoleOwner, HighestRole(models.RoleCollaborator, models.RoleOwner))
+
+	// CASE no access
+	require.Equal(t, "", HighestRole())
+	require.Equal(t, "", HighestRole("unknown"))
+}
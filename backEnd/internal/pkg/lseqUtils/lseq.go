@@ -0,0 +1,180 @@
+package lseqUtils
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math/rand/v2"
+
+	"github.com/evanrmtl/miniDoc/internal/pkg/convertUtils"
+)
+
+// Digits bounds match the sentinels of the frontend LSEQ.
+const (
+	MinDigit = 0
+	MaxDigit = 10000000
+	Boundary = 1000
+	MaxDepth = 64
+)
+
+var (
+	ErrInvalidInterval = errors.New("left position must be strictly lower than right position")
+	ErrMaxDepth        = errors.New("no position available between the two neighbours")
+)
+
+// Identifier is one level of a position: the digit and the site that allocated it.
+// The site breaks ties between digits allocated concurrently at the same spot.
+type Identifier struct {
+	Digit int
+	Site  int
+}
+
+// Position is a path in the LSEQ tree. It is stored through convertUtils
+// as the flat list [digit, site, digit, site, ...].
+type Position []Identifier
+
+// SiteFromSession derives the site ID of a session from its UUID.
+func SiteFromSession(sessionUUID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(sessionUUID))
+	return int(hash.Sum32())
+}
+
+func FromInts(path []int) Position {
+	position := make(Position, 0, (len(path)+1)/2)
+	for i := 0; i < len(path); i += 2 {
+		id := Identifier{Digit: path[i]}
+		if i+1 < len(path) {
+			id.Site = path[i+1]
+		}
+		position = append(position, id)
+	}
+	return position
+}
+
+func (p Position) Ints() []int {
+	out := make([]int, 0, len(p)*2)
+	for _, id := range p {
+		out = append(out, id.Digit, id.Site)
+	}
+	return out
+}
+
+func Encode(p Position) []byte {
+	return convertUtils.SliceIntToByte(p.Ints())
+}
+
+func Decode(data []byte) Position {
+	return FromInts(convertUtils.SliceByteToSliceInt(data))
+}
+
+// SortKey encodes the position so that the bytewise order of the keys, which is the
+// order of the database, is the order of the positions: each int on 8 bytes big-endian.
+func SortKey(p Position) []byte {
+	key := make([]byte, 0, len(p)*16)
+	for _, value := range p.Ints() {
+		key = binary.BigEndian.AppendUint64(key, uint64(value))
+	}
+	return key
+}
+
+// Compare orders two encoded paths. It returns a negative number if a is before b,
+// a positive number if a is after b and 0 if they are the same position.
+func Compare(a []byte, b []byte) int {
+	return ComparePositions(Decode(a), Decode(b))
+}
+
+// ComparePositions orders positions level by level, digit first then site.
+// A position that is a prefix of another one comes first.
+func ComparePositions(a Position, b Position) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if cmp := compareIdentifier(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(a) - len(b)
+}
+
+func compareIdentifier(a Identifier, b Identifier) int {
+	if a.Digit != b.Digit {
+		if a.Digit < b.Digit {
+			return -1
+		}
+		return 1
+	}
+	if a.Site != b.Site {
+		if a.Site < b.Site {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// Between allocates an encoded path strictly between left and right.
+// A nil left means the beginning of the document, a nil right its end.
+func Between(left []byte, right []byte, site int) ([]byte, error) {
+	var p, q Position
+	if left != nil {
+		p = Decode(left)
+	}
+	if right != nil {
+		q = Decode(right)
+	}
+
+	position, err := Alloc(p, q, site)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(position), nil
+}
+
+// Alloc allocates a position strictly between p and q, LSEQ style: it goes down
+// the tree until there is room between the two neighbours, then picks a digit
+// close to p (boundary+) or close to q (boundary-) depending on the depth.
+func Alloc(p Position, q Position, site int) (Position, error) {
+	if len(q) > 0 && ComparePositions(p, q) >= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	// boundToQ is true while the allocated prefix is still equal to the prefix of q.
+	boundToQ := len(q) > 0
+	prefix := make(Position, 0, len(p)+1)
+
+	for depth := 0; depth < MaxDepth; depth++ {
+		if boundToQ && depth >= len(q) {
+			return nil, ErrInvalidInterval
+		}
+
+		low := MinDigit
+		if depth < len(p) {
+			low = p[depth].Digit
+		}
+		high := MaxDigit
+		if boundToQ {
+			high = q[depth].Digit
+		}
+
+		if interval := high - low - 1; interval > 0 {
+			step := min(Boundary, interval)
+			digit := low + 1 + rand.IntN(step)
+			if depth%2 == 1 {
+				digit = high - 1 - rand.IntN(step)
+			}
+			return append(prefix, Identifier{Digit: digit, Site: site}), nil
+		}
+
+		id := Identifier{Digit: low, Site: site}
+		if depth < len(p) {
+			id = p[depth]
+		} else if boundToQ && compareIdentifier(id, q[depth]) > 0 {
+			id = q[depth]
+		}
+		prefix = append(prefix, id)
+
+		if boundToQ && compareIdentifier(id, q[depth]) != 0 {
+			boundToQ = false
+		}
+	}
+	return nil, ErrMaxDepth
+}
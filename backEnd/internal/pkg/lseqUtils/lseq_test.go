@@ -0,0 +1,126 @@
+package lseqUtils
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	position := Position{{Digit: 12, Site: 3}, {Digit: 9999999, Site: 4000000000}}
+	require.Equal(t, position, Decode(Encode(position)))
+
+	// CASE odd number of ints, the last digit has no site
+	require.Equal(t, Position{{Digit: 5, Site: 1}, {Digit: 7}}, FromInts([]int{5, 1, 7}))
+}
+
+func TestComparePositions(t *testing.T) {
+	a := Encode(Position{{Digit: 5, Site: 1}})
+	b := Encode(Position{{Digit: 5, Site: 2}})
+	c := Encode(Position{{Digit: 5, Site: 1}, {Digit: 1, Site: 9}})
+	d := Encode(Position{{Digit: 6, Site: 0}})
+
+	require.Negative(t, Compare(a, b))
+	require.Positive(t, Compare(b, a))
+	require.Negative(t, Compare(a, c))
+	require.Negative(t, Compare(c, b))
+	require.Negative(t, Compare(b, d))
+	require.Zero(t, Compare(c, c))
+}
+
+func TestSortKey(t *testing.T) {
+	positions := []Position{
+		{{Digit: 5, Site: 1}},
+		{{Digit: 5, Site: 2}},
+		{{Digit: 5, Site: 1}, {Digit: 1, Site: 9}},
+		{{Digit: 6, Site: 0}},
+		{{Digit: 300, Site: 4000000000}},
+		{{Digit: 300, Site: 4000000000}, {Digit: 0, Site: 0}},
+		{{Digit: MaxDigit, Site: 1}},
+	}
+
+	// CASE the keys are ordered like the positions, the encoded paths aren't
+	for _, a := range positions {
+		for _, b := range positions {
+			require.Equal(t, sign(ComparePositions(a, b)), bytes.Compare(SortKey(a), SortKey(b)), "%v %v", a, b)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestAllocStrictlyBetween(t *testing.T) {
+	site := SiteFromSession("123456-123456-123456")
+
+	// CASE empty document
+	first, err := Between(nil, nil, site)
+	require.NoError(t, err)
+
+	// CASE always inserting at the same spot goes down the tree
+	left := first
+	right := []byte(nil)
+	for i := 0; i < 2000; i++ {
+		middle, err := Between(left, right, site)
+		require.NoError(t, err)
+		require.Negative(t, Compare(left, middle))
+		if right != nil {
+			require.Negative(t, Compare(middle, right))
+		}
+		right = middle
+	}
+
+	// CASE neighbours with the same digit but different sites
+	p := Position{{Digit: 10, Site: 1}}
+	q := Position{{Digit: 10, Site: 2}}
+	middle, err := Alloc(p, q, site)
+	require.NoError(t, err)
+	require.Negative(t, ComparePositions(p, middle))
+	require.Negative(t, ComparePositions(middle, q))
+
+	// CASE right is a child of left with the lowest digit
+	q = Position{{Digit: 10, Site: 1}, {Digit: 0, Site: 5}, {Digit: 3, Site: 5}}
+	middle, err = Alloc(p, q, 9)
+	require.NoError(t, err)
+	require.Negative(t, ComparePositions(p, middle))
+	require.Negative(t, ComparePositions(middle, q))
+
+	// CASE invalid interval
+	_, err = Alloc(q, p, site)
+	require.ErrorIs(t, err, ErrInvalidInterval)
+	_, err = Alloc(p, p, site)
+	require.ErrorIs(t, err, ErrInvalidInterval)
+}
+
+func TestConcurrentInsertsConverge(t *testing.T) {
+	siteA := SiteFromSession("session-a")
+	siteB := SiteFromSession("session-b")
+
+	left := Encode(Position{{Digit: 100, Site: siteA}})
+	right := Encode(Position{{Digit: 101, Site: siteA}})
+
+	fromA, err := Between(left, right, siteA)
+	require.NoError(t, err)
+	fromB, err := Between(left, right, siteB)
+	require.NoError(t, err)
+
+	require.NotZero(t, Compare(fromA, fromB))
+
+	// every server sorting the characters, whatever the arrival order, ends with the same sequence
+	orderOne := [][]byte{right, fromB, left, fromA}
+	orderTwo := [][]byte{fromA, left, right, fromB}
+	sort.Slice(orderOne, func(i, j int) bool { return Compare(orderOne[i], orderOne[j]) < 0 })
+	sort.Slice(orderTwo, func(i, j int) bool { return Compare(orderTwo[i], orderTwo[j]) < 0 })
+	require.Equal(t, orderOne, orderTwo)
+	require.Equal(t, left, orderOne[0])
+	require.Equal(t, right, orderOne[3])
+}
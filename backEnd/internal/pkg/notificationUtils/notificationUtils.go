@@ -0,0 +1,203 @@
+package notificationUtils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/evanrmtl/miniDoc/internal/app/models"
+	"github.com/evanrmtl/miniDoc/internal/common"
+	"github.com/evanrmtl/miniDoc/internal/pkg/accessUtils"
+	"github.com/evanrmtl/miniDoc/internal/pkg/redisUtils"
+	"gorm.io/gorm"
+)
+
+// Notify stores the notification in the inbox of its target user, then sends it
+// to their live sessions. A user without session reads it at their next connection.
+func Notify(notification common.UserNotification, ctx context.Context, db *gorm.DB) error {
+	payload, err := json.Marshal(notification.FileData)
+	if err != nil {
+		return err
+	}
+
+	stored := models.Notification{
+		UserID:           notification.TargetUser,
+		NotificationType: notification.NotificationType,
+		Payload:          string(payload),
+		CreatedAt:        time.Now().Unix(),
+	}
+	err = gorm.G[models.Notification](db).Create(ctx, &stored)
+	if err != nil {
+		return err
+	}
+
+	return redisUtils.BroadcastNotification(ctx, FromModel(stored))
+}
+
+// FromModel returns the stored notification as sent on the websocket.
+func FromModel(notification models.Notification) common.UserNotification {
+	return common.UserNotification{
+		NotificationID:   notification.NotificationID,
+		NotificationType: notification.NotificationType,
+		TargetUser:       notification.UserID,
+		FileData:         json.RawMessage(notification.Payload),
+		Read:             notification.Read,
+		CreatedAt:        notification.CreatedAt,
+	}
+}
+
+// Unread returns the most recent unread notifications of the user, oldest first.
+func Unread(userID uint32, limit int, ctx context.Context, db *gorm.DB) ([]common.UserNotification, error) {
+	stored, err := gorm.G[models.Notification](db).
+		Where("user_id = ?", userID).
+		Where("read = ?", false).
+		Order("created_at desc").
+		Limit(limit).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]common.UserNotification, len(stored))
+	for i, notification := range stored {
+		notifications[len(stored)-1-i] = FromModel(notification)
+	}
+	return notifications, nil
+}
+
+// NotifyFileShared sends the file_shared notification, with the current members of the file,
+// to every given user.
+func NotifyFileShared(fileUUID string, userIDs []uint32, ctx context.Context, db *gorm.DB) error {
+	sharedFile, err := gorm.G[models.File](db).Where("file_uuid = ?", fileUUID).First(ctx)
+	if err != nil {
+		return err
+	}
+
+	var users []common.SharedUsers
+	err = db.Table("users").
+		Select("users.username, users_files.role").
+		Joins("JOIN users_files ON users.user_id = users_files.user_id").
+		Where("users_files.file_uuid = ?", fileUUID).
+		Scan(&users).Error
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		newNotification := common.UserNotification{
+			NotificationType: "file_shared",
+			TargetUser:       userID,
+			FileData: common.ShareFileData{
+				FileUUID:      sharedFile.FileUUID,
+				FileName:      sharedFile.FileName,
+				FileUpdatedAt: time.Now().Unix(),
+				SharedUser:    users,
+			},
+		}
+
+		err = Notify(newNotification, ctx, db)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAccessChanged tells every given user their effective role on the file after one of
+// their accesses changed: file_revoke when none is left, role_changed otherwise.
+func NotifyAccessChanged(fileUUID string, userIDs []uint32, ctx context.Context, db *gorm.DB) error {
+	for _, userID := range userIDs {
+		role, err := accessUtils.GetRole(userID, fileUUID, ctx, db)
+		if err != nil && !errors.Is(err, accessUtils.ErrNoAccess) {
+			return err
+		}
+
+		newNotification := common.UserNotification{
+			NotificationType: "role_changed",
+			TargetUser:       userID,
+			FileData: common.RoleFileData{
+				FileUUID: fileUUID,
+				Role:     role,
+			},
+		}
+		if errors.Is(err, accessUtils.ErrNoAccess) {
+			newNotification.NotificationType = "file_revoke"
+			newNotification.FileData = common.RevokeFileData{
+				FileUUID: fileUUID,
+			}
+		}
+
+		err = Notify(newNotification, ctx, db)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyWorkspaceJoined sends the workspace_joined notification to the new member.
+func NotifyWorkspaceJoined(workspace models.Workspace, userID uint32, role string, ctx context.Context, db *gorm.DB) error {
+	return Notify(common.UserNotification{
+		NotificationType: "workspace_joined",
+		TargetUser:       userID,
+		FileData: common.WorkspaceData{
+			WorkspaceID: workspace.WorkspaceID,
+			Name:        workspace.Name,
+			Role:        role,
+		},
+	}, ctx, db)
+}
+
+// NotifyAccessRequested sends the access_requested notification to the owner of the file.
+func NotifyAccessRequested(request models.AccessRequest, ctx context.Context, db *gorm.DB) error {
+	requestedFile, err := gorm.G[models.File](db).Where("file_uuid = ?", request.FileUUID).First(ctx)
+	if err != nil {
+		return err
+	}
+	requester, err := gorm.G[models.User](db).Where("user_id = ?", request.UserID).First(ctx)
+	if err != nil {
+		return err
+	}
+	owner, err := gorm.G[models.UsersFile](db).Where("file_uuid = ?", request.FileUUID).Where("role = ?", models.RoleOwner).First(ctx)
+	if err != nil {
+		return err
+	}
+
+	return Notify(common.UserNotification{
+		NotificationType: "access_requested",
+		TargetUser:       owner.UserID,
+		FileData: common.AccessRequestData{
+			RequestID: request.RequestID,
+			FileUUID:  requestedFile.FileUUID,
+			FileName:  requestedFile.FileName,
+			Username:  requester.Username,
+			Message:   request.Message,
+		},
+	}, ctx, db)
+}
+
+// NotifyAccessResolved sends the access_request_resolved notification to the requester,
+// followed by file_shared when the request was approved.
+func NotifyAccessResolved(request models.AccessRequest, ctx context.Context, db *gorm.DB) error {
+	requestedFile, err := gorm.G[models.File](db).Where("file_uuid = ?", request.FileUUID).First(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = Notify(common.UserNotification{
+		NotificationType: "access_request_resolved",
+		TargetUser:       request.UserID,
+		FileData: common.AccessResolvedData{
+			RequestID: request.RequestID,
+			FileUUID:  requestedFile.FileUUID,
+			FileName:  requestedFile.FileName,
+			Status:    request.Status,
+			Role:      request.Role,
+		},
+	}, ctx, db)
+	if err != nil || request.Status != models.AccessRequestApproved {
+		return err
+	}
+	return NotifyFileShared(request.FileUUID, []uint32{request.UserID}, ctx, db)
+}
@@ -10,8 +10,9 @@ import (
 )
 
 const (
-	ChanUserNotification string = "user_notifications"
-	ChanFileEvent        string = "file_events"
+	ChanUserNotification  string = "user_notifications"
+	ChanFileEvent         string = "file_events"
+	ChanDocumentOperation string = "document_operations"
 )
 
 func PubRedis(ctx context.Context, channel string, msg interface{}) error {
@@ -29,14 +30,6 @@ func PubRedis(ctx context.Context, channel string, msg interface{}) error {
 	return nil
 }
 
-func PublishUserSharedNotification(ctx context.Context, notification common.UserNotification) error {
-	return BroadcastNotification(ctx, notification)
-}
-
-func PublishUserRevokeNotification(ctx context.Context, notification common.UserNotification) error {
-	return BroadcastNotification(ctx, notification)
-}
-
 func BroadcastNotification(ctx context.Context, notification common.UserNotification) error {
 	if notificationRouter != nil {
 		notificationRouter.RouteEvent(notification)
@@ -58,3 +51,12 @@ func BroadcastToDocument(ctx context.Context, event common.FileEvent) error {
 	event.ServerName = os.Getenv("SERVER_NAME")
 	return PubRedis(ctx, ChanFileEvent, event)
 }
+
+func BroadcastDocumentOperation(ctx context.Context, operation common.DocumentOperation) error {
+	if notificationRouter != nil {
+		notificationRouter.RouteEvent(operation)
+	}
+
+	operation.ServerName = os.Getenv("SERVER_NAME")
+	return PubRedis(ctx, ChanDocumentOperation, operation)
+}
@@ -41,7 +41,7 @@ func StartSubscriber(ctx context.Context) {
 }
 
 func subRedis(ctx context.Context) error {
-	pubsub := redisConnection.client.Subscribe(ctx, ChanUserNotification, ChanFileEvent)
+	pubsub := redisConnection.client.Subscribe(ctx, ChanUserNotification, ChanFileEvent, ChanDocumentOperation)
 	defer pubsub.Close()
 
 	_, err := pubsub.Receive(ctx)
@@ -94,6 +94,18 @@ func subRedis(ctx context.Context) error {
 					continue
 				}
 				go HandleDocEvent(ctx, event)
+			case ChanDocumentOperation:
+				var operation common.DocumentOperation
+				err := json.Unmarshal([]byte(msg.Payload), &operation)
+				if err != nil {
+					log.Printf("Failed unmarshalling document operation: %v", err)
+					continue
+				}
+
+				if operation.ServerName == currentServer {
+					continue
+				}
+				go HandleDocumentOperation(ctx, operation)
 			}
 		}
 	}
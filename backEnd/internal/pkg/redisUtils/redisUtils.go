@@ -2,8 +2,11 @@ package redisUtils
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/evanrmtl/miniDoc/internal/common"
 )
@@ -17,6 +20,10 @@ type SessionMetadata struct {
 
 type FileMetadata = []string
 
+// PresenceTTL is how long a presence lasts without heartbeat. The presence of a session
+// whose server stopped without removing it disappears after this delay.
+const PresenceTTL = 90 * time.Second
+
 var notificationRouter common.NotificationRouter
 
 func StoreSessionInRedis(userID uint32, sessionUUID string, ctx context.Context) {
@@ -63,6 +70,112 @@ func GetFileFromSession(sessionUUID string, ctx context.Context) string {
 	return redisConnection.client.HGet(ctx, "session:"+sessionUUID, "file_uuid").Val()
 }
 
+// SetSessionRevision stores the last revision of the file acknowledged by the session.
+func SetSessionRevision(fileUUID string, sessionUUID string, revision int64, ctx context.Context) {
+	err := redisConnection.client.HSet(ctx, "file_revisions:"+fileUUID, sessionUUID, revision).Err()
+	if err != nil {
+		log.Printf("Error updating acknowledged revision of session: %v", err)
+	}
+}
+
+func DeleteSessionRevision(fileUUID string, sessionUUID string, ctx context.Context) {
+	err := redisConnection.client.HDel(ctx, "file_revisions:"+fileUUID, sessionUUID).Err()
+	if err != nil {
+		log.Printf("Error deleting acknowledged revision of session: %v", err)
+	}
+}
+
+// GetConnectedSessions returns the sessions connected to the file, on any server, with the
+// revision each one acknowledged. Entries of sessions that no longer exist are removed.
+func GetConnectedSessions(fileUUID string, ctx context.Context) (map[string]int64, error) {
+	values, err := redisConnection.client.HGetAll(ctx, "file_revisions:"+fileUUID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[string]int64, len(values))
+	for sessionUUID, value := range values {
+		exists, err := redisConnection.client.Exists(ctx, "session:"+sessionUUID).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			DeleteSessionRevision(fileUUID, sessionUUID, ctx)
+			continue
+		}
+
+		sessionRevision, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions[sessionUUID] = sessionRevision
+	}
+	return revisions, nil
+}
+
+// AddPresence stores the presence of the session in the file, visible from every server.
+// It expires after PresenceTTL unless refreshed.
+func AddPresence(fileUUID string, sessionUUID string, presence interface{}, ctx context.Context) {
+	payload, err := json.Marshal(presence)
+	if err != nil {
+		log.Printf("error marshaling presence : %v\n", presence)
+		return
+	}
+
+	err = redisConnection.client.HSet(ctx, "file_presence:"+fileUUID, sessionUUID, payload).Err()
+	if err != nil {
+		log.Printf("Error adding presence in file: %v", err)
+	}
+	err = redisConnection.client.Set(ctx, presenceKey(fileUUID, sessionUUID), 1, PresenceTTL).Err()
+	if err != nil {
+		log.Printf("Error adding presence heartbeat: %v", err)
+	}
+}
+
+// RefreshPresence is the heartbeat of the presence of a live session.
+func RefreshPresence(fileUUID string, sessionUUID string, ctx context.Context) {
+	err := redisConnection.client.Expire(ctx, presenceKey(fileUUID, sessionUUID), PresenceTTL).Err()
+	if err != nil {
+		log.Printf("Error refreshing presence in file: %v", err)
+	}
+}
+
+func DeletePresence(fileUUID string, sessionUUID string, ctx context.Context) {
+	err := redisConnection.client.HDel(ctx, "file_presence:"+fileUUID, sessionUUID).Err()
+	if err != nil {
+		log.Printf("Error deleting presence in file: %v", err)
+	}
+	err = redisConnection.client.Del(ctx, presenceKey(fileUUID, sessionUUID)).Err()
+	if err != nil {
+		log.Printf("Error deleting presence heartbeat: %v", err)
+	}
+}
+
+// GetPresences returns the stored presences of the file, by session UUID.
+// The presences whose heartbeat expired are removed.
+func GetPresences(fileUUID string, ctx context.Context) (map[string]string, error) {
+	presences, err := redisConnection.client.HGetAll(ctx, "file_presence:"+fileUUID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for sessionUUID := range presences {
+		exists, err := redisConnection.client.Exists(ctx, presenceKey(fileUUID, sessionUUID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			DeletePresence(fileUUID, sessionUUID, ctx)
+			delete(presences, sessionUUID)
+		}
+	}
+	return presences, nil
+}
+
+func presenceKey(fileUUID string, sessionUUID string) string {
+	return "presence:" + fileUUID + ":" + sessionUUID
+}
+
 func SetEventRouter(router common.NotificationRouter) {
 	notificationRouter = router
 }
@@ -78,3 +191,9 @@ func HandleDocEvent(ctx context.Context, event common.FileEvent) {
 		notificationRouter.RouteEvent(event)
 	}
 }
+
+func HandleDocumentOperation(ctx context.Context, operation common.DocumentOperation) {
+	if notificationRouter != nil {
+		notificationRouter.RouteEvent(operation)
+	}
+}
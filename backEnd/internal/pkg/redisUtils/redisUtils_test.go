@@ -0,0 +1,53 @@
+package redisUtils
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	CreateRedis(context.Background())
+	os.Exit(m.Run())
+}
+
+func TestGetPresences(t *testing.T) {
+	ctx := t.Context()
+	fileUUID := "11111111-1111-1111-1111-111111111111"
+	defer redisConnection.client.Del(context.Background(), "file_presence:"+fileUUID)
+
+	AddPresence(fileUUID, "live", map[string]string{"username": "live"}, ctx)
+	defer DeletePresence(fileUUID, "live", context.Background())
+	AddPresence(fileUUID, "crashed", map[string]string{"username": "crashed"}, ctx)
+
+	// CASE every presence with a heartbeat is listed
+	presences, err := GetPresences(fileUUID, ctx)
+	require.NoError(t, err)
+	require.Len(t, presences, 2)
+
+	ttl, err := redisConnection.client.TTL(ctx, presenceKey(fileUUID, "live")).Result()
+	require.NoError(t, err)
+	require.Positive(t, ttl)
+	require.LessOrEqual(t, ttl, PresenceTTL)
+
+	// CASE the presence whose heartbeat expired is removed
+	require.NoError(t, redisConnection.client.Del(ctx, presenceKey(fileUUID, "crashed")).Err())
+	RefreshPresence(fileUUID, "live", ctx)
+
+	presences, err = GetPresences(fileUUID, ctx)
+	require.NoError(t, err)
+	require.Len(t, presences, 1)
+	require.Contains(t, presences, "live")
+
+	stored, err := redisConnection.client.HKeys(ctx, "file_presence:"+fileUUID).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{"live"}, stored)
+
+	// CASE a left session is gone at once
+	DeletePresence(fileUUID, "live", ctx)
+	presences, err = GetPresences(fileUUID, ctx)
+	require.NoError(t, err)
+	require.Empty(t, presences)
+}
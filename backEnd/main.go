@@ -9,6 +9,8 @@ import (
 	"syscall"
 	"time"
 
+	document "github.com/evanrmtl/miniDoc/internal/app/Document"
+	file "github.com/evanrmtl/miniDoc/internal/app/File"
 	database "github.com/evanrmtl/miniDoc/internal/app/database"
 	"github.com/evanrmtl/miniDoc/internal/app/websocket"
 	routes "github.com/evanrmtl/miniDoc/internal/middleware"
@@ -42,13 +44,17 @@ func main() {
 	redisUtils.CreateRedis(ctx)
 	redisUtils.StartSubscriber(ctx)
 
+	go document.CompactDocuments(ctx, db)
+	go file.DeleteExpiredShares(ctx, db)
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
 	<-sigCh
 	cancel()
 
-	shutdownCtx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
 	srv.Shutdown(shutdownCtx)
 
 	log.Println("Shutdown requested, server and routines shut down cleanly.")
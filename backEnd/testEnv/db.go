@@ -68,7 +68,21 @@ func Setup() error {
 		&models.FileMigration{},
 		&models.SessionMigration{},
 		&models.UsersFileMigration{},
-		&models.FilesContents{},
+		&models.FilesContentsMigration{},
+		&models.DocumentOperationMigration{},
+		&models.DocumentSnapshotMigration{},
+		&models.FileVersionMigration{},
+		&models.FileInviteMigration{},
+		&models.GroupMigration{},
+		&models.GroupMemberMigration{},
+		&models.GroupFileMigration{},
+		&models.WorkspaceMigration{},
+		&models.WorkspaceMemberMigration{},
+		&models.AccessRequestMigration{},
+		&models.NotificationMigration{},
+		&models.CommentThreadMigration{},
+		&models.CommentMigration{},
+		&models.SuggestionMigration{},
 	)
 	if err != nil {
 		log.Fatalln("error when migrating models")
@@ -94,6 +108,106 @@ func Setup() error {
 		log.Printf("Warning: constraint fk_files_contents_files_uuid already exist or error while creating it : %v", err)
 	}
 
+	err = DB.Exec("ALTER TABLE document_operations ADD CONSTRAINT fk_document_operations_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_document_operations_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE document_snapshots ADD CONSTRAINT fk_document_snapshots_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_document_snapshots_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE file_versions ADD CONSTRAINT fk_file_versions_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_file_versions_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE file_invites ADD CONSTRAINT fk_file_invites_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_file_invites_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE group_members ADD CONSTRAINT fk_group_members_group_id FOREIGN KEY (group_id) REFERENCES groups(group_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_members_group_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE group_members ADD CONSTRAINT fk_group_members_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_members_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE group_files ADD CONSTRAINT fk_group_files_group_id FOREIGN KEY (group_id) REFERENCES groups(group_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_files_group_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE group_files ADD CONSTRAINT fk_group_files_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_group_files_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE workspace_members ADD CONSTRAINT fk_workspace_members_workspace_id FOREIGN KEY (workspace_id) REFERENCES workspaces(workspace_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_workspace_members_workspace_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE workspace_members ADD CONSTRAINT fk_workspace_members_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_workspace_members_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE files ADD CONSTRAINT fk_files_workspace_id FOREIGN KEY (workspace_id) REFERENCES workspaces(workspace_id) ON DELETE SET NULL").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_files_workspace_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE access_requests ADD CONSTRAINT fk_access_requests_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_access_requests_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE access_requests ADD CONSTRAINT fk_access_requests_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_access_requests_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE notifications ADD CONSTRAINT fk_notifications_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_notifications_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE comment_threads ADD CONSTRAINT fk_comment_threads_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comment_threads_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE comment_threads ADD CONSTRAINT fk_comment_threads_created_by FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comment_threads_created_by already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE comments ADD CONSTRAINT fk_comments_thread_id FOREIGN KEY (thread_id) REFERENCES comment_threads(thread_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comments_thread_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE comments ADD CONSTRAINT fk_comments_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_comments_user_id already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE suggestions ADD CONSTRAINT fk_suggestions_file_uuid FOREIGN KEY (file_uuid) REFERENCES files(file_uuid) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_suggestions_file_uuid already exist or error while creating it : %v", err)
+	}
+
+	err = DB.Exec("ALTER TABLE suggestions ADD CONSTRAINT fk_suggestions_user_id FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE").Error
+	if err != nil {
+		log.Printf("Warning: constraint fk_suggestions_user_id already exist or error while creating it : %v", err)
+	}
+
 	fmt.Println("Migration successful")
 
 	return nil
@@ -112,6 +226,20 @@ func CleanTables() {
 		DB.Exec("TRUNCATE files RESTART IDENTITY CASCADE")
 		DB.Exec("TRUNCATE session RESTART IDENTITY CASCADE")
 		DB.Exec("TRUNCATE files_contents RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE document_operations RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE document_snapshots RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE file_versions RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE file_invites RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE groups RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE group_members RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE group_files RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE workspaces RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE workspace_members RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE access_requests RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE notifications RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE comment_threads RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE comments RESTART IDENTITY CASCADE")
+		DB.Exec("TRUNCATE suggestions RESTART IDENTITY CASCADE")
 	}
 }
 